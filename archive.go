@@ -0,0 +1,100 @@
+package rebed
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// archiveModTime is the modification time of archive entries whose
+// embedded modification time is unset, as is always the case for embed.FS.
+var archiveModTime = time.Unix(0, 0).UTC()
+
+// zipModTime is like archiveModTime for zip archives which
+// can't represent dates before 1980.
+var zipModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// WriteTar writes fsys to w as a tar archive preserving its folder
+// structure. Entries are written in the lexical order of Walk so the same
+// fsys always yields the same archive. Filter options are honored.
+func WriteTar(fsys fs.FS, w io.Writer, opts ...Option) error {
+	cfg := newConfig(opts)
+	tw := tar.NewWriter(w)
+	err := cfg.read(fsys, func(dirpath string, de fs.DirEntry) error {
+		info, err := de.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(dirpath, de.Name())
+		if de.IsDir() {
+			hdr.Name += "/"
+		}
+		if hdr.ModTime.IsZero() {
+			hdr.ModTime = archiveModTime
+		}
+		err = tw.WriteHeader(hdr)
+		if err != nil || de.IsDir() {
+			return err
+		}
+		f, err := fsys.Open(hdr.Name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return copyBuffer(tw, f)
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// WriteZip writes fsys to w as a zip archive preserving its folder
+// structure. Folders are written as directory entries and files are
+// deflated. Like WriteTar entries are written in lexical order so the same
+// fsys always yields the same archive. Filter options are honored.
+func WriteZip(fsys fs.FS, w io.Writer, opts ...Option) error {
+	cfg := newConfig(opts)
+	zw := zip.NewWriter(w)
+	err := cfg.read(fsys, func(dirpath string, de fs.DirEntry) error {
+		info, err := de.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		name := path.Join(dirpath, de.Name())
+		hdr.Name = name
+		hdr.Method = zip.Deflate
+		if de.IsDir() {
+			hdr.Name += "/"
+			hdr.Method = zip.Store
+		}
+		if hdr.Modified.IsZero() {
+			hdr.Modified = zipModTime
+		}
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil || de.IsDir() {
+			return err
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return copyBuffer(fw, f)
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
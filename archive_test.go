@@ -0,0 +1,108 @@
+package rebed_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/soypat/rebed"
+)
+
+func TestWriteTar(t *testing.T) {
+	var buf bytes.Buffer
+	err := rebed.WriteTar(testFS, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(bytes.NewReader(buf.Bytes()))
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		got, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := testFS.ReadFile(hdr.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%q: content mismatch, got %q, want %q", hdr.Name, got, want)
+		}
+	}
+	want := []string{"testFS/", "testFS/file", "testFS/folder/", "testFS/folder/fileInfolder",
+		"testFS/folder/subfolder/", "testFS/folder/subfolder/fileinsubfolder"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got entries %q, want %q", names, want)
+	}
+
+	var again bytes.Buffer
+	err = rebed.WriteTar(testFS, &again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Error("archive is not reproducible")
+	}
+}
+
+func TestWriteZip(t *testing.T) {
+	var buf bytes.Buffer
+	err := rebed.WriteZip(testFS, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, zf := range zr.File {
+		names = append(names, zf.Name)
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := testFS.ReadFile(zf.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%q: content mismatch, got %q, want %q", zf.Name, got, want)
+		}
+	}
+	want := []string{"testFS/", "testFS/file", "testFS/folder/", "testFS/folder/fileInfolder",
+		"testFS/folder/subfolder/", "testFS/folder/subfolder/fileinsubfolder"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got entries %q, want %q", names, want)
+	}
+
+	var again bytes.Buffer
+	err = rebed.WriteZip(testFS, &again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Error("archive is not reproducible")
+	}
+}
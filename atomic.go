@@ -0,0 +1,133 @@
+package rebed
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// embedCopyToFileAtomic is like embedCopyToFile but writes to a
+// temporary file which is renamed to dst once the copy succeeded, so
+// dst is never left truncated. The temporary file is created in dst's
+// folder so the rename never crosses filesystems. It is removed on error.
+func embedCopyToFileAtomic(wfs WriteFS, fsys fs.FS, path, dst string, perm os.FileMode, copyFn copyFunc) (err error) {
+	fi, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fi.Close()
+	fo, name, err := createTemp(wfs, filepath.Dir(dst), filepath.Base(dst), perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			fo.Close()
+			removeFile(wfs, name)
+		}
+	}()
+	err = copyFn(fo, fi)
+	if err != nil {
+		return err
+	}
+	err = fo.Close()
+	if err != nil {
+		return err
+	}
+	return renameFile(wfs, name, dst)
+}
+
+// createTemp creates a new hidden file in dir with perm before umask
+// for writing and returns it with its name.
+func createTemp(wfs WriteFS, dir, base string, perm os.FileMode) (io.WriteCloser, string, error) {
+	seed := uint64(time.Now().UnixNano())
+	for i := uint64(0); i < 1000; i++ {
+		name := filepath.Join(dir, "."+base+"."+strconv.FormatUint(seed+i, 36)+".tmp")
+		f, err := wfs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return f, name, err
+	}
+	return nil, "", &fs.PathError{Op: "createtemp", Path: filepath.Join(dir, "."+base+".*.tmp"), Err: fs.ErrExist}
+}
+
+// mkdirTemp is like createTemp for folders. Unlike os.MkdirTemp
+// the folder is created with perm before umask.
+func mkdirTemp(dir, base string, perm os.FileMode) (string, error) {
+	seed := uint64(time.Now().UnixNano())
+	for i := uint64(0); i < 1000; i++ {
+		name := filepath.Join(dir, "."+base+"."+strconv.FormatUint(seed+i, 36)+".tmp")
+		err := os.Mkdir(name, perm)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return name, err
+	}
+	return "", &fs.PathError{Op: "mkdirtemp", Path: filepath.Join(dir, "."+base+".*.tmp"), Err: fs.ErrExist}
+}
+
+// checkReplaceable fails if the folder at dest can't be replaced by
+// renaming another one to it since it is the working directory or a root.
+func checkReplaceable(dest string) error {
+	abs, err := filepath.Abs(dest)
+	if err != nil {
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if abs == wd || filepath.Dir(abs) == abs {
+		return &fs.PathError{Op: "replace", Path: dest, Err: fs.ErrInvalid}
+	}
+	return nil
+}
+
+// CreateTransactional is like CreateTo but either replaces dest with a
+// complete extraction of fsys or leaves it untouched. Files are written to a
+// new folder next to dest which is renamed to dest once every file
+// succeeded, after the folder previously at dest, if any, is moved aside
+// and before it is removed. On error the new folder is removed. dest is
+// replaced as a whole so files in it which are not in fsys are lost. It
+// always acts on the OS filesystem. dest can't be renamed if it is the
+// working directory, a filesystem root or a mount point: it fails with
+// fs.ErrInvalid for the first two before writing anything and the rename
+// fails for the last.
+func CreateTransactional(fsys fs.FS, dest string, opts ...Option) (err error) {
+	dest = filepath.Clean(dest)
+	if err := checkReplaceable(dest); err != nil {
+		return err
+	}
+	parent, base := filepath.Dir(dest), filepath.Base(dest)
+	stage, err := mkdirTemp(parent, base, newConfig(opts).dirPerm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(stage)
+		}
+	}()
+	err = CreateTo(fsys, stage, append(opts[:len(opts):len(opts)], WithWriteFS(osFS{}))...)
+	if err != nil {
+		return err
+	}
+	old := stage + ".old"
+	err = os.Rename(dest, old)
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.Rename(stage, dest)
+	} else if err != nil {
+		return err
+	}
+	err = os.Rename(stage, dest)
+	if err != nil {
+		// put the original back.
+		return errors.Join(err, os.Rename(old, dest))
+	}
+	return os.RemoveAll(old)
+}
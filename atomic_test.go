@@ -0,0 +1,132 @@
+package rebed_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/soypat/rebed"
+)
+
+func TestCreateAtomic(t *testing.T) {
+	dest := t.TempDir()
+	err := rebed.CreateTo(testFS, dest, rebed.WithAtomicWrites())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"testFS/file", "testFS/folder/fileInfolder"} {
+		want, err := testFS.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%q: content mismatch, got %q, want %q", name, got, want)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Join(dest, "testFS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected no temporary files left, got %d entries", len(entries))
+	}
+
+	// a failed copy must leave the original file untouched.
+	original := filepath.Join(dest, "testFS", "file")
+	err = os.WriteFile(original, []byte("original"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.CreateTo(readErrFS{testFS}, dest, rebed.WithAtomicWrites())
+	if !errors.Is(err, errRead) {
+		t.Errorf("expected %v, got %v", errRead, err)
+	}
+	b, err := os.ReadFile(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "original" {
+		t.Errorf("failed copy modified file, got %q", b)
+	}
+	entries, err = os.ReadDir(filepath.Join(dest, "testFS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected temporary file to be removed, got %d entries", len(entries))
+	}
+}
+
+var errRead = errors.New("read error")
+
+// readErrFS returns files which fail on Read.
+type readErrFS struct{ fs.FS }
+
+func (r readErrFS) Open(name string) (fs.File, error) {
+	f, err := r.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err == nil && info.IsDir() {
+		return f, nil
+	}
+	return readErrFile{f}, nil
+}
+
+type readErrFile struct{ fs.File }
+
+func (readErrFile) Read([]byte) (int, error) { return 0, errRead }
+
+func TestCreateTransactional(t *testing.T) {
+	parent := t.TempDir()
+	dest := filepath.Join(parent, "dest")
+	err := rebed.CreateTransactional(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFS(t, dest)
+
+	stale := filepath.Join(dest, "stale")
+	err = os.WriteFile(stale, []byte("old"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing := failReadFS{FS: testFS, name: "testFS/folder/fileInfolder"}
+	err = rebed.CreateTransactional(failing, dest)
+	if !errors.Is(err, errRead) {
+		t.Fatalf("expected %v, got %v", errRead, err)
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Errorf("expected original dest untouched on error: %v", err)
+	}
+
+	err = rebed.CreateTransactional(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFS(t, dest)
+	if _, err := os.Stat(stale); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected dest replaced, got %v", err)
+	}
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only dest left in its parent, got %d entries", len(entries))
+	}
+
+	// folders which can't be renamed are rejected before staging.
+	for _, dest := range []string{".", string(filepath.Separator)} {
+		err = rebed.CreateTransactional(testFS, dest)
+		if !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("%q: expected %v, got %v", dest, fs.ErrInvalid, err)
+		}
+	}
+}
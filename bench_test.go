@@ -0,0 +1,44 @@
+package rebed_test
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func BenchmarkCreateTinyFiles(b *testing.B) {
+	const nfiles = 10000
+	mfs := make(fstest.MapFS, nfiles)
+	for i := 0; i < nfiles; i++ {
+		mfs[fmt.Sprintf("d%d/f%d", i%100, i)] = &fstest.MapFile{Data: []byte("tiny")}
+	}
+	dest := b.TempDir()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := rebed.CreateTo(mfs, dest)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExtractorTinyFiles(b *testing.B) {
+	const nfiles = 10000
+	mfs := make(fstest.MapFS, nfiles)
+	for i := 0; i < nfiles; i++ {
+		mfs[fmt.Sprintf("d%d/f%d", i%100, i)] = &fstest.MapFile{Data: []byte("tiny")}
+	}
+	e := rebed.NewExtractor(mfs)
+	dest := b.TempDir()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := e.ExtractTo(dest)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
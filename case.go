@@ -0,0 +1,51 @@
+package rebed
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// ErrCaseCollision is returned by extractions with WithCaseCheck when
+// embedded paths differ only in case.
+var ErrCaseCollision = errors.New("paths differ only in case")
+
+// WithCaseCheck makes extraction check before writing anything that no two
+// paths of fsys differ only in case, such as README.md and readme.md, which
+// would overwrite each other on case insensitive filesystems like those of
+// macOS and Windows. This almost always indicates a packaging mistake.
+// The error wraps ErrCaseCollision and lists every colliding set of paths.
+func WithCaseCheck() Option {
+	return func(c *config) { c.caseCheck = true }
+}
+
+// checkCase returns an error listing the paths kept by
+// the filters which only differ in case.
+func (c *config) checkCase(fsys fs.FS) error {
+	seen := make(map[string][]string)
+	var folded []string // in walk order.
+	err := c.walkFiltered(fsys, func(dirpath string, de fs.DirEntry) error {
+		fullpath := path.Join(dirpath, de.Name())
+		lower := strings.ToLower(fullpath)
+		if seen[lower] == nil {
+			folded = append(folded, lower)
+		}
+		seen[lower] = append(seen[lower], fullpath)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	var collisions [][]string
+	for _, lower := range folded {
+		if len(seen[lower]) > 1 {
+			collisions = append(collisions, seen[lower])
+		}
+	}
+	if len(collisions) != 0 {
+		return fmt.Errorf("rebed: %w: %q", ErrCaseCollision, collisions)
+	}
+	return nil
+}
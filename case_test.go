@@ -0,0 +1,50 @@
+package rebed_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func TestCaseCheck(t *testing.T) {
+	fsys := fstest.MapFS{
+		"README.md":   {Data: []byte("a")},
+		"readme.md":   {Data: []byte("b")},
+		"docs/a.txt":  {},
+		"Docs/b.txt":  {},
+		"unique.txt":  {},
+		"web/App.js":  {},
+		"web/app.css": {},
+	}
+	dest := t.TempDir()
+	err := rebed.CreateTo(fsys, dest, rebed.WithCaseCheck())
+	if !errors.Is(err, rebed.ErrCaseCollision) {
+		t.Fatalf("expected %v, got %v", rebed.ErrCaseCollision, err)
+	}
+	for _, want := range []string{`["Docs" "docs"]`, `["README.md" "readme.md"]`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to list %s, got %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "unique.txt") || strings.Contains(err.Error(), "App.js") {
+		t.Errorf("expected only colliding paths listed, got %v", err)
+	}
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected nothing written, got %d entries", len(entries))
+	}
+
+	delete(fsys, "readme.md")
+	delete(fsys, "Docs/b.txt")
+	err = rebed.CreateTo(fsys, t.TempDir(), rebed.WithCaseCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,91 @@
+package rebed
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// WithCheckpoint makes Create resumable, i.e. when extracting a large
+// filesystem over a flaky network filesystem. Create appends the slash
+// separated path of every file it writes to the file at path, one JSON
+// string per line. If the file exists from an interrupted run the files
+// listed in it are skipped unless their contents differ from the embedded
+// ones. The file is removed once Create succeeds. CreateParallel
+// records and resumes the same way.
+func WithCheckpoint(path string) Option {
+	return func(c *config) { c.checkpoint = path }
+}
+
+// checkpoint records the files written by an extraction. A nil
+// *checkpoint records nothing. record is safe for concurrent use.
+type checkpoint struct {
+	mu   sync.Mutex
+	f    *os.File
+	done map[string]bool
+}
+
+// openCheckpoint loads the checkpoint file at path, if any, and
+// opens it for recording. It returns nil if path is empty.
+func openCheckpoint(path string) (*checkpoint, error) {
+	if path == "" {
+		return nil, nil
+	}
+	cp := &checkpoint{done: make(map[string]bool)}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var name string
+		// a line cut short by an interruption is ignored,
+		// its file and the next are copied again.
+		if json.Unmarshal(scanner.Bytes(), &name) == nil {
+			cp.done[name] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	cp.f = f
+	return cp, nil
+}
+
+// completed reports whether the embedded file at fullpath
+// was written by a previous run.
+func (cp *checkpoint) completed(fullpath string) bool {
+	return cp != nil && cp.done[fullpath]
+}
+
+// record adds the embedded file at fullpath to the checkpoint.
+func (cp *checkpoint) record(fullpath string) error {
+	if cp == nil {
+		return nil
+	}
+	b, err := json.Marshal(fullpath)
+	if err != nil {
+		return err
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	_, err = cp.f.Write(append(b, '\n'))
+	return err
+}
+
+// finish closes the checkpoint and removes it if the
+// extraction ended with a nil err. It returns err if not nil.
+func (cp *checkpoint) finish(err error) error {
+	if cp == nil {
+		return err
+	}
+	cerr := cp.f.Close()
+	if err != nil {
+		return err
+	} else if cerr != nil {
+		return cerr
+	}
+	return os.Remove(cp.f.Name())
+}
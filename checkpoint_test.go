@@ -0,0 +1,73 @@
+package rebed_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/soypat/rebed"
+)
+
+func TestCheckpoint(t *testing.T) {
+	for _, edit := range []bool{false, true} {
+		dest := t.TempDir()
+		cpPath := filepath.Join(t.TempDir(), "rebed.checkpoint")
+		// files are copied in lexical order so the
+		// interruption comes after testFS/file is written.
+		failing := failReadFS{FS: testFS, name: "testFS/folder/fileInfolder"}
+		err := rebed.CreateTo(failing, dest, rebed.WithCheckpoint(cpPath))
+		if !errors.Is(err, errRead) {
+			t.Fatalf("expected %v, got %v", errRead, err)
+		}
+		b, err := os.ReadFile(cpPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "\"testFS/file\"\n" {
+			t.Errorf("got checkpoint %q", b)
+		}
+
+		completed := filepath.Join(dest, "testFS", "file")
+		want := "skip"
+		if edit {
+			want = "overwrite"
+			err = os.WriteFile(completed, []byte("edited"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		var got string
+		err = rebed.CreateTo(testFS, dest, rebed.WithCheckpoint(cpPath),
+			rebed.WithHook(func(path string, _ fs.DirEntry, action string) {
+				if path == completed {
+					got = action
+				}
+			}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("edited=%v: got action %q for completed file, want %q", edit, got, want)
+		}
+		if _, err := os.Stat(cpPath); !os.IsNotExist(err) {
+			t.Errorf("expected checkpoint removed on success, got %v", err)
+		}
+		assertMatchesFS(t, dest)
+	}
+}
+
+// failReadFS fails reading the file name.
+type failReadFS struct {
+	fs.FS
+	name string
+}
+
+func (f failReadFS) Open(name string) (fs.File, error) {
+	file, err := f.FS.Open(name)
+	if err != nil || name != f.name {
+		return file, err
+	}
+	return readErrFile{file}, nil
+}
@@ -0,0 +1,126 @@
+package rebed
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ChecksumMode selects where WithChecksums writes checksums.
+type ChecksumMode int
+
+const (
+	// ChecksumSidecar writes the checksum of every file to a file next to
+	// it with its name and a ".sha256" suffix.
+	ChecksumSidecar ChecksumMode = iota + 1
+	// ChecksumSums writes the checksums of all files to a single
+	// SHA256SUMS file at the root of dest once extraction succeeded.
+	ChecksumSums
+)
+
+// sumsFile is the name of the file ChecksumSums writes.
+const sumsFile = "SHA256SUMS"
+
+// sidecarSuffix is appended to the name of a file for the
+// name of the file ChecksumSidecar writes.
+const sidecarSuffix = ".sha256"
+
+// WithChecksums makes Create write the SHA-256 checksums of the files it
+// writes as selected by mode, in the format of sha256sum so they may be
+// checked with "sha256sum -c". The checksums are computed as the files are
+// written, without reading them again, so they are those of the contents on
+// disk, i.e. after decompression with WithGunzip. Links are not listed.
+// The functions which write every file, such as CreateTo, CreateParallel,
+// ExtractMixed and Sync, write the SHA256SUMS file once done. Those which
+// leave some files alone, CreateIfChanged, Restore, CreateMissing,
+// CreateNewer, ApplyDiff and ExtractFile, fail with errors.ErrUnsupported
+// with ChecksumSums as they can't list them. Sync does not remove the
+// checksum files as strays.
+func WithChecksums(mode ChecksumMode) Option {
+	return func(c *config) { c.checksums = &checksums{mode: mode} }
+}
+
+// checksums holds the checksums of an extraction.
+// It is safe for concurrent use.
+type checksums struct {
+	mode ChecksumMode
+	mu   sync.Mutex
+	dsts []string
+	sums [][]byte
+}
+
+// record records the checksum sum of the file dst written through wfs,
+// writing its sidecar file if set to.
+func (cs *checksums) record(wfs WriteFS, dst string, sum []byte) error {
+	if cs.mode == ChecksumSidecar {
+		return writeSums(wfs, dst+sidecarSuffix, []string{filepath.Base(dst)}, [][]byte{sum})
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.dsts = append(cs.dsts, dst)
+	cs.sums = append(cs.sums, sum)
+	return nil
+}
+
+// finish writes the SHA256SUMS file inside dest if set to.
+func (cs *checksums) finish(wfs WriteFS, dest string) error {
+	if cs == nil || cs.mode != ChecksumSums {
+		return nil
+	}
+	names := make([]string, len(cs.dsts))
+	for i, dst := range cs.dsts {
+		rel, err := filepath.Rel(dest, dst)
+		if err != nil {
+			return err
+		}
+		names[i] = filepath.ToSlash(rel)
+	}
+	// CreateParallel records files in no particular order.
+	sort.Sort(sumsByName{names, cs.sums})
+	return writeSums(wfs, filepath.Join(dest, sumsFile), names, cs.sums)
+}
+
+// sumsByName sorts checksums by the name of their file.
+type sumsByName struct {
+	names []string
+	sums  [][]byte
+}
+
+func (s sumsByName) Len() int           { return len(s.names) }
+func (s sumsByName) Less(i, j int) bool { return s.names[i] < s.names[j] }
+func (s sumsByName) Swap(i, j int) {
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+	s.sums[i], s.sums[j] = s.sums[j], s.sums[i]
+}
+
+// sumsUnsupported fails if ChecksumSums is set, for the function fn
+// which leaves files alone and so can't list the checksums of all of them.
+func (c *config) sumsUnsupported(fn string) error {
+	if c.checksums != nil && c.checksums.mode == ChecksumSums {
+		return fmt.Errorf("rebed: %s does not support ChecksumSums: %w", fn, errors.ErrUnsupported)
+	}
+	return nil
+}
+
+// writeSums writes a file at name listing the checksum of every file in
+// names in the format of sha256sum.
+func writeSums(wfs WriteFS, name string, names []string, sums [][]byte) error {
+	var buf bytes.Buffer
+	for i := range names {
+		fmt.Fprintf(&buf, "%s  %s\n", hex.EncodeToString(sums[i]), names[i])
+	}
+	f, err := wfs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(buf.Bytes())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
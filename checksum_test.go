@@ -0,0 +1,100 @@
+package rebed_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/soypat/rebed"
+)
+
+func TestChecksums(t *testing.T) {
+	files := []string{"testFS/file", "testFS/folder/fileInfolder", "testFS/folder/subfolder/fileinsubfolder"}
+	sum := func(name string) string {
+		b, err := testFS.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprintf("%x", sha256.Sum256(b))
+	}
+
+	dest := t.TempDir()
+	err := rebed.CreateTo(testFS, dest, rebed.WithChecksums(rebed.ChecksumSidecar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFS(t, dest)
+	for _, name := range files {
+		got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)+".sha256"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := sum(name) + "  " + filepath.Base(name) + "\n"; string(got) != want {
+			t.Errorf("%q: got sidecar %q, want %q", name, got, want)
+		}
+	}
+
+	dest = t.TempDir()
+	err = rebed.CreateTo(testFS, dest, rebed.WithChecksums(rebed.ChecksumSums))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "SHA256SUMS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want string
+	for _, name := range files {
+		want += sum(name) + "  " + name + "\n"
+	}
+	if string(got) != want {
+		t.Errorf("got SHA256SUMS %q, want %q", got, want)
+	}
+
+	dest = t.TempDir()
+	err = rebed.ExtractMixed(testFS, dest, func(string) rebed.Mode { return rebed.ModeCreate },
+		rebed.WithChecksums(rebed.ChecksumSums))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = os.ReadFile(filepath.Join(dest, "SHA256SUMS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("ExtractMixed: got SHA256SUMS %q, want %q", got, want)
+	}
+
+	// functions leaving files alone can't list them all.
+	sums := rebed.WithChecksums(rebed.ChecksumSums)
+	for name, fn := range map[string]func() error{
+		"CreateIfChanged": func() error { _, err := rebed.CreateIfChanged(testFS, dest, sums); return err },
+		"CreateMissing":   func() error { return rebed.CreateMissing(testFS, dest, sums) },
+		"CreateNewer":     func() error { return rebed.CreateNewer(testFS, dest, time.Time{}, sums) },
+		"ExtractFile":     func() error { return rebed.ExtractFile(testFS, files[0], dest, sums) },
+	} {
+		if err := fn(); !errors.Is(err, errors.ErrUnsupported) {
+			t.Errorf("%s: expected %v, got %v", name, errors.ErrUnsupported, err)
+		}
+	}
+}
+
+func TestChecksumsSync(t *testing.T) {
+	for mode, name := range map[rebed.ChecksumMode]string{
+		rebed.ChecksumSidecar: "testFS/folder/fileInfolder.sha256",
+		rebed.ChecksumSums:    "SHA256SUMS",
+	} {
+		dest := t.TempDir()
+		err := rebed.Sync(testFS, dest, rebed.WithChecksums(mode))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name))); err != nil {
+			t.Errorf("expected %q kept, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,524 @@
+package rebed
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// CreateIfChanged is like CreateTo but only overwrites files whose contents
+// differ from their embedded counterpart, so unchanged files keep their
+// modification time. It returns the paths of the files which were written.
+func CreateIfChanged(fsys fs.FS, dest string, opts ...Option) ([]string, error) {
+	cfg := newConfig(opts)
+	if err := cfg.sumsUnsupported("CreateIfChanged"); err != nil {
+		return nil, err
+	}
+	var written []string
+	err := cfg.walk(fsys, func(dirpath string, de fs.DirEntry) error {
+		fullpath := path.Join(dirpath, de.Name())
+		dst, err := cfg.dst(dest, fullpath, de)
+		if err != nil {
+			return err
+		}
+		if de.IsDir() {
+			return cfg.mkdir(dst, de)
+		}
+		same, err := cfg.sameContents(fsys, fullpath, dst)
+		if err != nil {
+			return err
+		} else if same {
+			cfg.visit(dst, de, "skip")
+			return nil
+		}
+		err = cfg.writeFile(fsys, fullpath, dst, de)
+		if err == nil {
+			written = append(written, dst)
+		}
+		return err
+	})
+	return written, err
+}
+
+// CreateNewer is like CreateTo but skips the files whose counterpart on
+// disk was modified at or after since, i.e. the time of the previous
+// extraction, as embed.FS has no modification times to compare. Missing
+// files and files modified before since are written.
+func CreateNewer(fsys fs.FS, dest string, since time.Time, opts ...Option) error {
+	cfg := newConfig(opts)
+	if err := cfg.sumsUnsupported("CreateNewer"); err != nil {
+		return err
+	}
+	return cfg.walk(fsys, func(dirpath string, de fs.DirEntry) error {
+		fullpath := path.Join(dirpath, de.Name())
+		dst, err := cfg.dst(dest, fullpath, de)
+		if err != nil {
+			return err
+		}
+		if de.IsDir() {
+			return cfg.mkdir(dst, de)
+		}
+		info, err := cfg.wfs.Stat(dst)
+		if err == nil && !info.ModTime().Before(since) {
+			cfg.visit(dst, de, "skip")
+			return nil
+		} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return cfg.writeFile(fsys, fullpath, dst, de)
+	})
+}
+
+// Missing returns the slash separated paths of the files of fsys which
+// are not on disk in dest, in the order of Walk, i.e. to drive a custom
+// lazy extraction. These are the files CreateMissing would write and it
+// honors the same options: filtered files are never listed and renamed
+// ones are looked up at their new path.
+func Missing(fsys fs.FS, dest string, opts ...Option) ([]string, error) {
+	cfg := newConfig(opts)
+	var missing []string
+	err := cfg.read(fsys, func(dirpath string, de fs.DirEntry) error {
+		if de.IsDir() {
+			return nil
+		}
+		fullpath := path.Join(dirpath, de.Name())
+		dst, err := cfg.fileDst(dest, fullpath)
+		if err != nil {
+			return err
+		}
+		_, err = cfg.wfs.Stat(dst)
+		if errors.Is(err, fs.ErrNotExist) {
+			missing = append(missing, fullpath)
+			return nil
+		}
+		return err
+	})
+	return missing, err
+}
+
+// DiffResult holds the differences between an fs.FS and a folder on disk.
+// Paths are slash separated and relative to the root of both.
+// Parents are listed before their children.
+type DiffResult struct {
+	// Added holds the paths present in the fs.FS which are missing on disk.
+	Added []string
+	// Removed holds the paths present on disk which are missing in the fs.FS.
+	Removed []string
+	// Changed holds the paths present in both whose contents differ or
+	// which are a file in one and a folder in the other.
+	Changed []string
+}
+
+// Diff compares fsys against dest without modifying either.
+// File contents are streamed, not loaded into memory.
+// Diff is the read-only counterpart of Sync. The only option
+// it honors is WithTextNormalize.
+func Diff(fsys fs.FS, dest string, opts ...Option) (*DiffResult, error) {
+	cfg := newConfig(opts)
+	diff := &DiffResult{}
+	err := Walk(fsys, ".", func(dirpath string, de fs.DirEntry) error {
+		fullpath := path.Join(dirpath, de.Name())
+		missing, same, err := cfg.compareEntry(fsys, fullpath, de, filepath.Join(dest, filepath.FromSlash(fullpath)))
+		switch {
+		case err != nil:
+			return err
+		case missing:
+			diff.Added = append(diff.Added, fullpath)
+		case !same:
+			diff.Changed = append(diff.Changed, fullpath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == dest {
+			return err
+		}
+		rel, err := filepath.Rel(dest, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		_, err = fs.Stat(fsys, rel)
+		if os.IsNotExist(err) {
+			diff.Removed = append(diff.Removed, rel)
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// DiffFS compares two filesystems, i.e. an old embedded bundle against a
+// new one, without extracting either. Added holds the paths only in newFS,
+// Removed the paths only in oldFS and Changed those in both whose contents
+// differ or which are a file in one and a folder in the other. File
+// contents are streamed, not loaded into memory. The result may be passed
+// to ApplyDiff to update a folder extracted from oldFS.
+func DiffFS(oldFS, newFS fs.FS) (*DiffResult, error) {
+	diff := &DiffResult{}
+	err := Walk(newFS, ".", func(dirpath string, de fs.DirEntry) error {
+		fullpath := path.Join(dirpath, de.Name())
+		oldInfo, err := fs.Stat(oldFS, fullpath)
+		if errors.Is(err, fs.ErrNotExist) {
+			diff.Added = append(diff.Added, fullpath)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		same := de.IsDir() && oldInfo.IsDir()
+		if !de.IsDir() && !oldInfo.IsDir() {
+			same, err = sameFSFiles(oldFS, newFS, fullpath)
+			if err != nil {
+				return err
+			}
+		}
+		if !same {
+			diff.Changed = append(diff.Changed, fullpath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = Walk(oldFS, ".", func(dirpath string, de fs.DirEntry) error {
+		fullpath := path.Join(dirpath, de.Name())
+		_, err := fs.Stat(newFS, fullpath)
+		if errors.Is(err, fs.ErrNotExist) {
+			diff.Removed = append(diff.Removed, fullpath)
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// ApplyDiff updates dest, extracted from an older version of newFS, to
+// match newFS with the diff returned by DiffFS, touching as few files as
+// possible: removed files are deleted, added and changed ones written and
+// the rest left alone, keeping their modification times. Removed folders
+// are only deleted if empty so files users added to them are kept.
+func ApplyDiff(newFS fs.FS, dest string, diff *DiffResult, opts ...Option) error {
+	cfg := newConfig(opts)
+	if err := cfg.sumsUnsupported("ApplyDiff"); err != nil {
+		return err
+	}
+	for i := len(diff.Removed) - 1; i >= 0; i-- {
+		name := diff.Removed[i]
+		dst, err := joinDest(dest, name)
+		if err == nil {
+			err = removeIfEmpty(cfg.wfs, dst)
+		}
+		if err != nil {
+			return &PathError{Path: name, Err: err}
+		}
+	}
+	names := append(append([]string(nil), diff.Added...), diff.Changed...)
+	sort.Strings(names) // parents before children.
+	for _, name := range names {
+		err := cfg.apply(newFS, dest, name)
+		if err != nil {
+			return &PathError{Path: name, Err: err}
+		}
+	}
+	return cfg.stampDirs()
+}
+
+// apply writes the file or folder name of fsys inside dest, replacing a
+// file where a folder goes and a folder where a file goes. The folder's
+// embedded contents are removed before so it fails if it holds other files.
+func (c *config) apply(fsys fs.FS, dest, name string) error {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return err
+	}
+	de := fs.FileInfoToDirEntry(info)
+	dst, err := c.dst(dest, name, de)
+	if err != nil {
+		return err
+	}
+	if diskInfo, err := c.wfs.Stat(dst); err == nil && diskInfo.IsDir() != de.IsDir() {
+		err = removeFile(c.wfs, dst)
+		if err != nil {
+			return err
+		}
+	}
+	if !de.IsDir() {
+		return c.writeFile(fsys, name, dst, de)
+	}
+	return c.mkdir(dst, de)
+}
+
+// removeIfEmpty removes the file or empty folder name from wfs.
+// Missing and non empty folders are left alone.
+func removeIfEmpty(wfs WriteFS, name string) error {
+	err := removeFile(wfs, name)
+	if err == nil || errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if info, serr := wfs.Stat(name); serr == nil && info.IsDir() {
+		return nil // holds files which are not embedded.
+	}
+	return err
+}
+
+// sameFSFiles reports whether the file name has equal
+// contents in both filesystems.
+func sameFSFiles(a, b fs.FS, name string) (bool, error) {
+	fa, err := a.Open(name)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := b.Open(name)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+	return sameFiles(fa, fb)
+}
+
+// ErrMismatch is returned by Verify when a path on disk
+// differs from its embedded counterpart.
+var ErrMismatch = errors.New("differs from embedded file")
+
+// Verify checks every file and folder of fsys is present in dest with
+// the same contents without modifying anything. The returned error is
+// an *fs.PathError naming the first offending path on disk which wraps
+// fs.ErrNotExist for missing paths or ErrMismatch for differing ones.
+// Paths in dest which are not in fsys are ignored. The only option
+// it honors is WithTextNormalize.
+func Verify(fsys fs.FS, dest string, opts ...Option) error {
+	cfg := newConfig(opts)
+	return Walk(fsys, ".", func(dirpath string, de fs.DirEntry) error {
+		fullpath := path.Join(dirpath, de.Name())
+		dst := filepath.Join(dest, filepath.FromSlash(fullpath))
+		missing, same, err := cfg.compareEntry(fsys, fullpath, de, dst)
+		switch {
+		case err != nil:
+			return err
+		case missing:
+			return &fs.PathError{Op: "verify", Path: dst, Err: fs.ErrNotExist}
+		case !same:
+			return &fs.PathError{Op: "verify", Path: dst, Err: ErrMismatch}
+		}
+		return nil
+	})
+}
+
+// compareEntry compares the embedded entry de at path against dst on disk.
+// Folders are the same if both are folders, files if their contents match.
+func (c *config) compareEntry(fsys fs.FS, path string, de fs.DirEntry, dst string) (missing, same bool, err error) {
+	info, err := os.Stat(dst)
+	if os.IsNotExist(err) {
+		return true, false, nil
+	} else if err != nil {
+		return false, false, err
+	}
+	if de.IsDir() || info.IsDir() {
+		return false, de.IsDir() && info.IsDir(), nil
+	}
+	if c.normalize != nil {
+		text, err := c.normalize.Match(path)
+		if err != nil || text {
+			same, err = sameText(fsys, path, dst)
+			return false, same, err
+		}
+	}
+	same, err = sameContents(osFS{}, fsys, path, dst)
+	return false, same, err
+}
+
+// WithTextNormalize makes Diff and Verify ignore cosmetic edits, such as
+// those made by an editor, to the files selected by text: a leading UTF-8
+// byte order mark, whitespace at the end of lines, line endings and blank
+// lines at the end of the file. Files selected by text are read into memory
+// to be compared, other files are compared byte for byte.
+func WithTextNormalize(text Globs) Option {
+	return func(c *config) { c.normalize = &text }
+}
+
+// sameText reports whether the embedded file at path and the
+// file dst on disk are the same text once normalized.
+func sameText(fsys fs.FS, path, dst string) (bool, error) {
+	embedded, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return false, err
+	}
+	onDisk, err := os.ReadFile(dst)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(normalizeText(embedded), normalizeText(onDisk)), nil
+}
+
+// normalizeText strips the byte order mark, the whitespace
+// ending lines and the blank lines ending text.
+func normalizeText(text []byte) []byte {
+	text = bytes.TrimPrefix(text, []byte("\ufeff"))
+	lines := bytes.Split(text, []byte("\n"))
+	for i, line := range lines {
+		lines[i] = bytes.TrimRight(line, " \t\r")
+	}
+	return bytes.TrimRight(bytes.Join(lines, []byte("\n")), "\n")
+}
+
+// sameContents reports whether the file dst in c.wfs holds what writing
+// the embedded file at path would, once decompressed, executed as a
+// template, converted to c.lineEnding or compressed as set. A missing dst
+// is reported as not equal. Files which are written verbatim are compared
+// as by the sameContents function.
+func (c *config) sameContents(fsys fs.FS, path, dst string) (bool, error) {
+	transformed, err := c.transformed(path)
+	if err != nil || !transformed {
+		if err != nil {
+			return false, err
+		}
+		return sameContents(c.wfs, fsys, path, dst)
+	}
+	fo, err := openFile(c.wfs, dst)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer fo.Close()
+	fi, err := fsys.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer fi.Close()
+	// nothing is written so nothing is counted.
+	quiet := *c
+	quiet.stats = nil
+	cw := &cmpWriter{r: fo}
+	err = quiet.copyContents(path, cw, fi, nil)
+	if err != nil || cw.differ {
+		return false, err
+	}
+	return cw.atEOF()
+}
+
+// transformed reports whether the contents written for the embedded
+// file at path may differ from its embedded contents.
+func (c *config) transformed(path string) (bool, error) {
+	if c.gunzipped(path) {
+		return true, nil
+	}
+	if templated, err := c.templated(path); err != nil || templated {
+		return templated, err
+	}
+	if c.text != nil {
+		if text, err := c.text.Match(path); err != nil || text {
+			return text, err
+		}
+	}
+	return c.gzipped(path)
+}
+
+// cmpWriter compares the bytes written to it against those read from r.
+type cmpWriter struct {
+	r      io.Reader
+	buf    []byte
+	differ bool
+}
+
+func (w *cmpWriter) Write(b []byte) (int, error) {
+	if w.differ {
+		return len(b), nil
+	}
+	if cap(w.buf) < len(b) {
+		w.buf = make([]byte, len(b))
+	}
+	n, err := io.ReadFull(w.r, w.buf[:len(b)])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return 0, err
+	}
+	w.differ = !bytes.Equal(w.buf[:n], b)
+	return len(b), nil
+}
+
+// atEOF reports whether everything was read from r, which
+// means r held no more bytes than written to w.
+func (w *cmpWriter) atEOF() (bool, error) {
+	n, err := io.ReadFull(w.r, make([]byte, 1))
+	if n == 0 && err == io.EOF {
+		return true, nil
+	}
+	return false, err
+}
+
+// sameContents reports whether the embedded file at path and the file
+// dst in wfs have equal contents. A missing dst is reported as not equal.
+// Sizes are compared first and contents are streamed so neither file
+// is read into memory in full.
+func sameContents(wfs WriteFS, fsys fs.FS, path, dst string) (bool, error) {
+	fo, err := openFile(wfs, dst)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer fo.Close()
+	fi, err := fsys.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer fi.Close()
+	return sameFiles(fi, fo)
+}
+
+// sameFiles reports whether the open files a and b have equal contents.
+func sameFiles(a, b fs.File) (bool, error) {
+	aInfo, err := a.Stat()
+	if err != nil {
+		return false, err
+	}
+	bInfo, err := b.Stat()
+	if err != nil {
+		return false, err
+	}
+	if aInfo.IsDir() || bInfo.IsDir() || aInfo.Size() != bInfo.Size() {
+		return false, nil
+	}
+	return sameReaders(a, b)
+}
+
+// sameReaders reports whether a and b yield the same bytes.
+func sameReaders(a, b io.Reader) (bool, error) {
+	bufpa, bufpb := bufPool.Get().(*[]byte), bufPool.Get().(*[]byte)
+	defer bufPool.Put(bufpa)
+	defer bufPool.Put(bufpb)
+	bufa, bufb := *bufpa, *bufpb
+	for {
+		na, erra := io.ReadFull(a, bufa)
+		nb, errb := io.ReadFull(b, bufb)
+		if !bytes.Equal(bufa[:na], bufb[:nb]) {
+			return false, nil
+		}
+		aDone := erra == io.EOF || erra == io.ErrUnexpectedEOF
+		bDone := errb == io.EOF || errb == io.ErrUnexpectedEOF
+		switch {
+		case erra != nil && !aDone:
+			return false, erra
+		case errb != nil && !bDone:
+			return false, errb
+		case aDone || bDone:
+			return aDone == bDone, nil
+		}
+	}
+}
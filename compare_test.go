@@ -0,0 +1,416 @@
+package rebed_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/soypat/rebed"
+)
+
+func TestCreateIfChanged(t *testing.T) {
+	dest := t.TempDir()
+	written, err := rebed.CreateIfChanged(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 3 {
+		t.Errorf("expected all 3 files written to empty folder, got %q", written)
+	}
+	assertMatchesFS(t, dest)
+
+	unchanged := filepath.Join(dest, "testFS", "file")
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	err = os.Chtimes(unchanged, old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := filepath.Join(dest, "testFS", "folder", "fileInfolder")
+	// same size, different contents.
+	b, err := os.ReadFile(changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[len(b)-1]++
+	err = os.WriteFile(changed, b, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err = rebed.CreateIfChanged(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 1 || written[0] != changed {
+		t.Errorf("expected only %q written, got %q", changed, written)
+	}
+	assertMatchesFS(t, dest)
+	info, err := os.Stat(unchanged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(old) {
+		t.Errorf("unchanged file was rewritten, modtime %v", info.ModTime())
+	}
+}
+
+func TestCreateIfChangedTransformed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt.gz": {Data: gzipData(t, "hello\n")},
+		"b.txt":    {Data: []byte("line\n")},
+	}
+	opts := []rebed.Option{rebed.WithGunzip(), rebed.WithLineEnding(rebed.CRLF, rebed.Globs{Include: []string{"*.txt"}})}
+	dest := t.TempDir()
+	written, err := rebed.CreateIfChanged(fsys, dest, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 2 {
+		t.Errorf("expected 2 files written to empty folder, got %q", written)
+	}
+	written, err = rebed.CreateIfChanged(fsys, dest, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 0 {
+		t.Errorf("expected unchanged files left alone, got %q", written)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dest := t.TempDir()
+	err := rebed.CreateTo(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := rebed.Diff(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed) != 0 {
+		t.Errorf("expected no differences after Create, got %+v", diff)
+	}
+	err = os.WriteFile(filepath.Join(dest, "testFS", "file"), []byte("changed"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.RemoveAll(filepath.Join(dest, "testFS", "folder", "subfolder"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(filepath.Join(dest, "stray", "folder"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err = rebed.Diff(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &rebed.DiffResult{
+		Added:   []string{"testFS/folder/subfolder", "testFS/folder/subfolder/fileinsubfolder"},
+		Removed: []string{"stray", "stray/folder"},
+		Changed: []string{"testFS/file"},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("got diff %+v, want %+v", diff, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dest := t.TempDir()
+	err := rebed.CreateTo(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dest, "stray"), []byte("ignored"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.Verify(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed := filepath.Join(dest, "testFS", "folder", "fileInfolder")
+	err = os.WriteFile(changed, []byte("changed"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.Verify(testFS, dest)
+	var perr *fs.PathError
+	if !errors.Is(err, rebed.ErrMismatch) || !errors.As(err, &perr) || perr.Path != changed {
+		t.Errorf("expected mismatch error for %q, got %v", changed, err)
+	}
+
+	missing := filepath.Join(dest, "testFS", "file")
+	err = os.Remove(missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.Verify(testFS, dest)
+	if !errors.Is(err, fs.ErrNotExist) || !errors.As(err, &perr) || perr.Path != missing {
+		t.Errorf("expected not exist error for %q, got %v", missing, err)
+	}
+}
+
+var (
+	oldFS = fstest.MapFS{
+		"same.txt":        {Data: []byte("same")},
+		"changed.txt":     {Data: []byte("old")},
+		"resized.txt":     {Data: []byte("old")},
+		"gone/file.txt":   {Data: []byte("gone")},
+		"kind":            {Data: []byte("file")},
+		"dir/kept.txt":    {Data: []byte("kept")},
+		"dir/removed.txt": {Data: []byte("removed")},
+	}
+	newFS = fstest.MapFS{
+		"same.txt":       {Data: []byte("same")},
+		"changed.txt":    {Data: []byte("new")},
+		"resized.txt":    {Data: []byte("longer")},
+		"added/file.txt": {Data: []byte("added")},
+		"kind/file.txt":  {Data: []byte("folder now")},
+		"dir/kept.txt":   {Data: []byte("kept")},
+	}
+)
+
+func TestDiffFS(t *testing.T) {
+	diff, err := rebed.DiffFS(oldFS, newFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &rebed.DiffResult{
+		Added:   []string{"added", "added/file.txt", "kind/file.txt"},
+		Removed: []string{"dir/removed.txt", "gone", "gone/file.txt"},
+		Changed: []string{"changed.txt", "kind", "resized.txt"},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("got %+v, want %+v", diff, want)
+	}
+	diff, err = rebed.DiffFS(newFS, newFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed) != 0 {
+		t.Errorf("expected no differences, got %+v", diff)
+	}
+}
+
+func TestApplyDiff(t *testing.T) {
+	dest := t.TempDir()
+	err := rebed.CreateTo(oldFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := filepath.Join(dest, "same.txt")
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	err = os.Chtimes(kept, old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userFile := filepath.Join(dest, "gone", "user.txt")
+	err = os.WriteFile(userFile, []byte("not embedded"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := rebed.DiffFS(oldFS, newFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.ApplyDiff(newFS, dest, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.Verify(newFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"dir/removed.txt", "gone/file.txt"} {
+		_, err = os.Stat(filepath.Join(dest, filepath.FromSlash(name)))
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected %q removed, got %v", name, err)
+		}
+	}
+	_, err = os.Stat(userFile)
+	if err != nil {
+		t.Errorf("file not in old bundle was removed: %v", err)
+	}
+	info, err := os.Stat(kept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(old) {
+		t.Errorf("unchanged file was rewritten, modtime %v", info.ModTime())
+	}
+}
+
+func TestApplyDiffFolderToFile(t *testing.T) {
+	oldFS := fstest.MapFS{"x/child": {Data: []byte("child")}}
+	newFS := fstest.MapFS{"x": {Data: []byte("x")}}
+	dest := t.TempDir()
+	err := rebed.CreateTo(oldFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := rebed.DiffFS(oldFS, newFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.ApplyDiff(newFS, dest, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.Verify(newFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// files users added to the folder are not removed.
+	dest = t.TempDir()
+	err = rebed.CreateTo(oldFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userFile := filepath.Join(dest, "x", "user.txt")
+	err = os.WriteFile(userFile, []byte("not embedded"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.ApplyDiff(newFS, dest, diff)
+	var perr *rebed.PathError
+	if !errors.As(err, &perr) || perr.Path != "x" {
+		t.Errorf("expected error naming %q, got %v", "x", err)
+	}
+	if _, err := os.Stat(userFile); err != nil {
+		t.Errorf("file not in old bundle was removed: %v", err)
+	}
+}
+
+func TestMissing(t *testing.T) {
+	dest := t.TempDir()
+	all := []string{"testFS/file", "testFS/folder/fileInfolder", "testFS/folder/subfolder/fileinsubfolder"}
+	missing, err := rebed.Missing(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(missing, all) {
+		t.Errorf("got %q, want %q", missing, all)
+	}
+	err = rebed.CreateManifest(testFS, dest, []string{"testFS/folder/fileInfolder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing, err = rebed.Missing(testFS, dest, rebed.WithFilter(func(path string, _ fs.DirEntry) bool {
+		return path != "testFS/file"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := all[2:]; !reflect.DeepEqual(missing, want) {
+		t.Errorf("got %q, want %q", missing, want)
+	}
+}
+
+func TestTextNormalize(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.yml": {Data: []byte("a: 1\nb: 2\n")},
+		"data.bin":   {Data: []byte("a: 1\n")},
+	}
+	dest := t.TempDir()
+	err := rebed.CreateTo(fsys, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		"config.yml": "\ufeffa: 1  \r\nb: 2\r\n\r\n",
+		"data.bin":   "a: 1\n\n",
+	} {
+		err = os.WriteFile(filepath.Join(dest, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	text := rebed.WithTextNormalize(rebed.Globs{Include: []string{"*.yml"}})
+	diff, err := rebed.Diff(fsys, dest, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"data.bin"}; !reflect.DeepEqual(diff.Changed, want) {
+		t.Errorf("got changed %q, want %q", diff.Changed, want)
+	}
+	err = os.Remove(filepath.Join(dest, "data.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.CreateTo(fsys, dest, rebed.WithFilter(func(path string, _ fs.DirEntry) bool { return path == "data.bin" }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.Verify(fsys, dest, text)
+	if err != nil {
+		t.Errorf("expected cosmetic edits ignored, got %v", err)
+	}
+	if err := rebed.Verify(fsys, dest); !errors.Is(err, rebed.ErrMismatch) {
+		t.Errorf("expected %v without normalization, got %v", rebed.ErrMismatch, err)
+	}
+	err = os.WriteFile(filepath.Join(dest, "config.yml"), []byte("a: 1\nb: 3\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rebed.Verify(fsys, dest, text); !errors.Is(err, rebed.ErrMismatch) {
+		t.Errorf("expected %v for edited contents, got %v", rebed.ErrMismatch, err)
+	}
+}
+
+func TestCreateNewer(t *testing.T) {
+	dest := t.TempDir()
+	err := rebed.CreateTo(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := time.Now().Add(-time.Minute)
+	stale := filepath.Join(dest, "testFS", "file")
+	old := since.Add(-time.Hour)
+	err = os.WriteFile(stale, []byte("stale"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chtimes(stale, old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh := filepath.Join(dest, "testFS", "folder", "fileInfolder")
+	err = os.WriteFile(fresh, []byte("user edit"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dest, "testFS", "folder", "subfolder", "fileinsubfolder")
+	err = os.Remove(missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.CreateNewer(testFS, dest, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedded, err := testFS.ReadFile("testFS/folder/subfolder/fileinsubfolder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		stale:   "file1",
+		fresh:   "user edit",
+		missing: string(embedded),
+	} {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%q: got %q, want %q", name, got, want)
+		}
+	}
+}
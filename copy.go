@@ -0,0 +1,324 @@
+package rebed
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// visit reports action on the path dst on disk to the hook
+// and logger, if any.
+func (c *config) visit(dst string, de fs.DirEntry, action string) {
+	if c.hook != nil {
+		c.hook(dst, de, action)
+	}
+	if c.logger != nil {
+		c.logger.visit(dst, de, action)
+	}
+}
+
+// mkdir creates the folder dst for the embedded folder de.
+// With a rename callback set folders are instead created
+// as needed by writeFile.
+func (c *config) mkdir(dst string, de fs.DirEntry) error {
+	if c.rename != nil {
+		return nil
+	}
+	existed := false
+	if c.stats != nil {
+		_, err := c.wfs.Stat(dst)
+		existed = err == nil
+	}
+	err := c.wfs.MkdirAll(dst, c.dirPerm)
+	if err == nil {
+		c.visit(dst, de, "mkdir")
+		if !c.modTime.IsZero() {
+			c.dirs = append(c.dirs, dst)
+		}
+		if c.stats != nil && !existed {
+			c.stats.DirsCreated++
+		}
+	}
+	return err
+}
+
+// mkdirParent creates the folder holding the file dst if folders are
+// not created from the embedded ones, see mkdir.
+func (c *config) mkdirParent(dst string) error {
+	if c.rename == nil {
+		return nil
+	}
+	return c.wfs.MkdirAll(filepath.Dir(dst), c.dirPerm)
+}
+
+// stampDirs sets the times of the folders created so far to the
+// time set by WithModTime. It must be called once their contents are
+// written as that changes their modification time.
+func (c *config) stampDirs() error {
+	for i := len(c.dirs) - 1; i >= 0; i-- {
+		err := chtimesFile(c.wfs, c.dirs[i], c.modTime, c.modTime)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile is like copyFile but consults the overwrite policy if dst
+// exists and reports to the hook whether dst was created or overwritten.
+func (c *config) writeFile(fsys fs.FS, path, dst string, de fs.DirEntry) error {
+	if err := c.mkdirParent(dst); err != nil {
+		return err
+	}
+	action := "create"
+	if c.hook != nil || c.overwrite != nil || c.logger != nil {
+		diskInfo, err := c.wfs.Stat(dst)
+		if err == nil {
+			action = "overwrite"
+		}
+		if err == nil && c.overwrite != nil {
+			embedInfo, err := de.Info()
+			if err != nil {
+				return err
+			}
+			switch c.overwrite(dst, embedInfo, diskInfo) {
+			case Skip:
+				c.visit(dst, de, "skip")
+				return nil
+			case Backup:
+				err = renameFile(c.wfs, dst, dst+".bak")
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	var written int64
+	if c.stats != nil {
+		written = c.stats.BytesWritten
+	}
+	err := c.retry(func() error {
+		if c.stats != nil {
+			c.stats.BytesWritten = written // forget failed attempts.
+		}
+		if target, isLink := c.link(path); isLink {
+			return symlink(c.wfs, target, dst)
+		}
+		return c.copyFile(fsys, path, dst)
+	})
+	if err == nil {
+		c.visit(dst, de, action)
+		if c.stats != nil {
+			c.stats.FilesWritten++
+		}
+	}
+	return err
+}
+
+// link reports whether the embedded file at path is written as a
+// symbolic link and its target.
+func (c *config) link(path string) (target string, isLink bool) {
+	if c.symlink == nil {
+		return "", false
+	}
+	return c.symlink(path)
+}
+
+// symlink creates a symbolic link to target at dst,
+// removing whatever file or link is at dst first.
+func symlink(wfs WriteFS, target, dst string) error {
+	err := removeFile(wfs, dst)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return symlinkFile(wfs, target, dst)
+}
+
+// copyFile copies the embedded file at path to dst on disk
+// honoring the file related options.
+func (c *config) copyFile(fsys fs.FS, path, dst string) error {
+	copyFile := embedCopyToFile
+	if c.atomic {
+		copyFile = embedCopyToFileAtomic
+	}
+	// new files are created with their final mode so they are
+	// never readable by more users than asked for.
+	perm := os.FileMode(0666)
+	if c.fileMode != nil {
+		perm = c.fileMode(path)
+	}
+	var sniff *sniffWriter
+	var h hash.Hash
+	var pw *progressWriter
+	err := copyFile(c.wfs, fsys, path, dst, perm, func(w io.Writer, src fs.File) error {
+		file := w
+		if c.progress != nil {
+			info, err := src.Stat()
+			if err != nil {
+				return err
+			}
+			pw = c.progress.writer(path, info.Size())
+		}
+		if c.checksums != nil {
+			h = sha256.New()
+			w = io.MultiWriter(w, h)
+		}
+		if c.detectExec && runtime.GOOS != "windows" {
+			sniff = &sniffWriter{w: w}
+			w = sniff
+		}
+		err := c.copyContents(path, w, src, pw)
+		if err == nil && c.durable {
+			err = syncFile(file)
+		}
+		return err
+	})
+	if err == nil && c.fileMode != nil {
+		// chmod as the mode files are created with is subject to umask.
+		err = chmodFile(c.wfs, dst, perm)
+	} else if err == nil && sniff != nil && sniff.executable() {
+		err = chmodFile(c.wfs, dst, 0755)
+	}
+	if err == nil && !c.modTime.IsZero() {
+		err = chtimesFile(c.wfs, dst, c.modTime, c.modTime)
+	}
+	if err == nil && c.checksums != nil {
+		err = c.checksums.record(c.wfs, dst, h.Sum(nil))
+	}
+	if err == nil && c.durable {
+		err = syncDir(c.wfs, filepath.Dir(dst))
+	}
+	if pw != nil && err == nil {
+		pw.done()
+	} else if pw != nil {
+		pw.undo() // the file may be written again by retry.
+	}
+	return err
+}
+
+// dst returns the path on disk inside dest the embedded file or
+// folder de at fullpath is written to.
+func (c *config) dst(dest, fullpath string, de fs.DirEntry) (string, error) {
+	if !de.IsDir() {
+		return c.fileDst(dest, fullpath)
+	}
+	dst, err := joinDest(dest, fullpath)
+	if err == nil {
+		err = c.checkLinks(dest, dst, true)
+	}
+	return dst, err
+}
+
+// fileDst returns the path on disk inside dest the embedded
+// file at fullpath is written to.
+func (c *config) fileDst(dest, fullpath string) (string, error) {
+	dst, err := joinDest(dest, c.fileName(fullpath))
+	if err == nil {
+		_, isLink := c.link(fullpath)
+		err = c.checkLinks(dest, dst, !isLink)
+	}
+	return dst, err
+}
+
+// fileName returns the slash separated path inside dest
+// the embedded file at fullpath is written to.
+func (c *config) fileName(fullpath string) string {
+	if c.rename != nil {
+		return c.rename(fullpath)
+	}
+	name := fullpath
+	if c.gunzipped(fullpath) {
+		name = strings.TrimSuffix(name, gzSuffix)
+	}
+	if c.template != nil && c.template.trimSuffix != "" {
+		if ok, _ := c.template.match(fullpath); ok {
+			name = strings.TrimSuffix(name, c.template.trimSuffix)
+		}
+	}
+	if ok, _ := c.gzipped(fullpath); ok {
+		name += gzSuffix
+	}
+	return name
+}
+
+// copyContents copies the contents of the embedded file src at
+// path to dst, decompressing it, executing it if it is a template,
+// compressing it and reporting progress to pw if not nil.
+func (c *config) copyContents(path string, dst io.Writer, src fs.File, pw *progressWriter) error {
+	if c.stats != nil {
+		dst = countWriter{w: dst, n: &c.stats.BytesWritten}
+	}
+	var zw *gzip.Writer
+	if gzipped, err := c.gzipped(path); err != nil {
+		return err
+	} else if gzipped {
+		zw = gzip.NewWriter(dst)
+		dst = zw
+	}
+	var lw *lineWriter
+	if c.text != nil {
+		text, err := c.text.Match(path)
+		if err != nil {
+			return err
+		}
+		if text {
+			lw = &lineWriter{w: dst, crlf: c.lineEnding == CRLF}
+			dst = lw
+		}
+	}
+	// progress is counted in the embedded contents, before line
+	// endings are converted and compression, as FileSize is.
+	if pw != nil {
+		pw.w = dst
+		dst = pw
+	}
+	var r io.Reader = src
+	if c.gunzipped(path) {
+		zr, err := gzip.NewReader(src)
+		if err != nil {
+			// a truncated header is reported as io.ErrUnexpectedEOF.
+			return fmt.Errorf("invalid gzip stream: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	templated, err := c.templated(path)
+	if err != nil {
+		return err
+	}
+	if templated {
+		err = executeTemplate(dst, r, path, c.template.data)
+	} else {
+		err = copyBuffer(dst, r)
+	}
+	if err == nil && lw != nil {
+		err = lw.flush()
+	}
+	if err == nil && zw != nil {
+		err = zw.Close() // writes the gzip trailer.
+	}
+	return err
+}
+
+const gzSuffix = ".gz"
+
+// gunzipped reports whether the embedded file at path is decompressed.
+func (c *config) gunzipped(path string) bool {
+	return c.gunzip && strings.HasSuffix(path, gzSuffix)
+}
+
+// gzipped reports whether the embedded file at path is compressed.
+func (c *config) gzipped(path string) (bool, error) {
+	if c.gzipOutput == "" {
+		return false, nil
+	}
+	return matchGlob(c.gzipOutput, path)
+}
@@ -0,0 +1,49 @@
+package rebed
+
+import (
+	"io/fs"
+	"runtime"
+)
+
+// WithDurable makes Create flush every file it writes to stable storage
+// before closing it, and then the folder holding it so its entry is
+// flushed too, i.e. for configs read right after a reboot which may follow
+// a power loss. It makes extraction much slower. Files and folders of a
+// WriteFS without a Sync method, like MemFS, are not flushed. Folders
+// are not flushed on Windows which does not support it.
+func WithDurable() Option {
+	return func(c *config) { c.durable = true }
+}
+
+// syncer is implemented by files which can be flushed, like *os.File.
+type syncer interface {
+	Sync() error
+}
+
+// syncFile flushes the open file f to stable storage if it can be.
+func syncFile(f any) error {
+	if s, ok := f.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// syncDir flushes the folder dir of wfs to stable storage if it can be.
+func syncDir(wfs WriteFS, dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	o, ok := wfs.(interface{ Open(string) (fs.File, error) })
+	if !ok {
+		return nil
+	}
+	f, err := o.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = syncFile(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
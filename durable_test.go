@@ -0,0 +1,57 @@
+package rebed_test
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/soypat/rebed"
+)
+
+func TestDurable(t *testing.T) {
+	for _, atomic := range []bool{false, true} {
+		dest := t.TempDir()
+		opts := []rebed.Option{rebed.WithDurable()}
+		if atomic {
+			opts = append(opts, rebed.WithAtomicWrites())
+		}
+		err := rebed.CreateTo(testFS, dest, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertMatchesFS(t, dest)
+	}
+
+	wfs := &syncFS{MemFS: &rebed.MemFS{}}
+	err := rebed.CreateTo(testFS, "out", rebed.WithWriteFS(wfs), rebed.WithDurable())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wfs.syncs != 3 {
+		t.Errorf("expected every file synced, got %d syncs", wfs.syncs)
+	}
+}
+
+// syncFS is a MemFS whose files count calls to Sync.
+type syncFS struct {
+	*rebed.MemFS
+	syncs int
+}
+
+func (s *syncFS) OpenFile(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+	w, err := s.MemFS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return syncFile{WriteCloser: w, syncs: &s.syncs}, nil
+}
+
+type syncFile struct {
+	io.WriteCloser
+	syncs *int
+}
+
+func (f syncFile) Sync() error {
+	*f.syncs++
+	return nil
+}
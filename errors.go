@@ -0,0 +1,15 @@
+package rebed
+
+// PathError records an error and the embedded path which caused it.
+// It is returned by the extraction functions so a failure deep in a
+// tree names the offending file or folder.
+type PathError struct {
+	// Path is the slash separated path in the embedded filesystem.
+	Path string
+	Err  error
+}
+
+func (e *PathError) Error() string { return "rebed: " + e.Path + ": " + e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *PathError) Unwrap() error { return e.Err }
@@ -0,0 +1,37 @@
+package rebed_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/soypat/rebed"
+)
+
+func TestPathError(t *testing.T) {
+	extractors := map[string]func() error{
+		"CreateTo": func() error { return rebed.CreateTo(readErrFS{testFS}, t.TempDir()) },
+		"CreateParallel": func() error {
+			return rebed.CreateParallel(readErrFS{testFS}, t.TempDir(), 1)
+		},
+		"ExtractFile": func() error {
+			return rebed.ExtractFile(readErrFS{testFS}, "testFS/file", t.TempDir())
+		},
+	}
+	for name, extract := range extractors {
+		err := extract()
+		var perr *rebed.PathError
+		if !errors.As(err, &perr) {
+			t.Fatalf("%s: expected a *rebed.PathError, got %v", name, err)
+		}
+		if perr.Path != "testFS/file" {
+			t.Errorf("%s: got path %q, want %q", name, perr.Path, "testFS/file")
+		}
+		if !errors.Is(err, errRead) {
+			t.Errorf("%s: expected error to wrap %v, got %v", name, errRead, err)
+		}
+		if !strings.Contains(err.Error(), "testFS/file") {
+			t.Errorf("%s: expected error message to name path, got %q", name, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package rebed
+
+import (
+	"context"
+	"io/fs"
+)
+
+// Event describes a file written by CreateEvents.
+type Event struct {
+	// Path is the path on disk of the file.
+	Path string
+	// Size is the size of the embedded file.
+	Size int64
+}
+
+// CreateEvents is like CreateContext but runs in a new goroutine and sends
+// an Event on the first channel for every file once it is written, i.e. to
+// drive an installer's UI. The first channel is closed once done, then the
+// second delivers the error stopping the extraction, if any, and is closed.
+// A consumer which stops reading events must cancel ctx so the goroutine
+// returns; extraction then stops with ctx's error.
+func CreateEvents(ctx context.Context, fsys fs.FS, dest string, opts ...Option) (<-chan Event, <-chan error) {
+	events := make(chan Event)
+	errc := make(chan error, 1)
+	send := func(c *config) {
+		prev := c.hook
+		c.hook = func(path string, de fs.DirEntry, action string) {
+			if prev != nil {
+				prev(path, de, action)
+			}
+			if action != "create" && action != "overwrite" {
+				return
+			}
+			ev := Event{Path: path}
+			if info, err := de.Info(); err == nil {
+				ev.Size = info.Size()
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+			}
+		}
+	}
+	// a full slice so append never writes to the caller's array.
+	opts = append(opts[:len(opts):len(opts)], send)
+	go func() {
+		err := CreateContext(ctx, fsys, dest, opts...)
+		close(events)
+		if err != nil {
+			errc <- err
+		}
+		close(errc)
+	}()
+	return events, errc
+}
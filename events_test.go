@@ -0,0 +1,42 @@
+package rebed_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/soypat/rebed"
+)
+
+func TestCreateEvents(t *testing.T) {
+	dest := t.TempDir()
+	events, errc := rebed.CreateEvents(context.Background(), testFS, dest)
+	var got []rebed.Event
+	for ev := range events {
+		got = append(got, ev)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected an event for each of 3 files, got %+v", got)
+	}
+	if want := filepath.Join(dest, "testFS", "file"); got[0].Path != want || got[0].Size != 5 {
+		t.Errorf("got first event %+v, want %q of size 5", got[0], want)
+	}
+	assertMatchesFS(t, dest)
+
+	// a consumer which stops reading cancels the context.
+	ctx, cancel := context.WithCancel(context.Background())
+	events, errc = rebed.CreateEvents(ctx, testFS, t.TempDir())
+	<-events
+	cancel()
+	err := <-errc
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if _, ok := <-events; ok {
+		t.Error("expected events channel closed")
+	}
+}
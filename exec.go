@@ -0,0 +1,47 @@
+package rebed
+
+import (
+	"bytes"
+	"io"
+)
+
+// WithDetectExecutable makes Create restore the executable bit embed.FS
+// loses on the files which look executable: scripts starting with a
+// shebang ("#!") and ELF or Mach-O binaries are given mode 0755. It only
+// applies on Unix-like systems, Windows has no executable bit. Files
+// matched by WithFileModeFunc get its mode instead.
+func WithDetectExecutable() Option {
+	return func(c *config) { c.detectExec = true }
+}
+
+// executableMagic holds the prefixes of executable files.
+var executableMagic = [][]byte{
+	[]byte("#!"),
+	[]byte("\x7fELF"),
+	{0xfe, 0xed, 0xfa, 0xce}, // Mach-O 32 bit.
+	{0xfe, 0xed, 0xfa, 0xcf}, // Mach-O 64 bit.
+	{0xce, 0xfa, 0xed, 0xfe}, // Mach-O 32 bit, little endian.
+	{0xcf, 0xfa, 0xed, 0xfe}, // Mach-O 64 bit, little endian.
+}
+
+// sniffWriter keeps the first bytes written to w.
+type sniffWriter struct {
+	w    io.Writer
+	head [4]byte
+	n    int
+}
+
+func (s *sniffWriter) Write(b []byte) (int, error) {
+	s.n += copy(s.head[s.n:], b)
+	return s.w.Write(b)
+}
+
+// executable reports whether the bytes written look like an executable.
+func (s *sniffWriter) executable() bool {
+	for _, magic := range executableMagic {
+		if bytes.HasPrefix(s.head[:s.n], magic) {
+			return true
+		}
+	}
+	return false
+}
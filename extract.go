@@ -0,0 +1,142 @@
+package rebed
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Extract extracts fsys inside dest with the behavior selected by mode,
+// i.e. Extract(fsys, dest, ModeCreate) is CreateTo(fsys, dest), so the
+// behavior may be chosen at runtime. It fails for an unknown mode.
+func Extract(fsys fs.FS, dest string, mode Mode, opts ...Option) error {
+	switch mode {
+	case ModeTree:
+		return TreeTo(fsys, dest, opts...)
+	case ModeTouch:
+		return TouchTo(fsys, dest, opts...)
+	case ModeCreate:
+		return CreateTo(fsys, dest, opts...)
+	case ModePatch:
+		return PatchTo(fsys, dest, opts...)
+	}
+	return fmt.Errorf("rebed: unknown mode %d", int(mode))
+}
+
+// ExtractMixed is like Extract but decide chooses the mode of every file
+// from its slash separated path, i.e. to copy templates with ModeCreate
+// and stub the files generated from them with ModeTouch, in a single pass.
+// Files for which it returns ModeTree are skipped. Folders are always
+// created. It fails for an unknown mode.
+func ExtractMixed(fsys fs.FS, dest string, decide func(path string) Mode, opts ...Option) error {
+	cfg := newConfig(opts)
+	err := cfg.walk(fsys, func(dirpath string, de fs.DirEntry) error {
+		fullpath := path.Join(dirpath, de.Name())
+		dst, err := cfg.dst(dest, fullpath, de)
+		if err != nil {
+			return err
+		}
+		if de.IsDir() {
+			return cfg.mkdir(dst, de)
+		}
+		switch mode := decide(fullpath); mode {
+		case ModeTree:
+			return nil
+		case ModeTouch, ModePatch:
+			_, err = cfg.touchFile(dst, de)
+			return err
+		case ModeCreate:
+			return cfg.writeFile(fsys, fullpath, dst, de)
+		default:
+			return fmt.Errorf("rebed: unknown mode %d", int(mode))
+		}
+	})
+	if err == nil {
+		err = cfg.checksums.finish(cfg.wfs, dest)
+	}
+	return err
+}
+
+// CreateTemp is like CreateTo into a new folder created in the default
+// folder for temporary files, i.e. to run an embedded tool, and returns its
+// path along with a function removing it with all it holds once done.
+// On error the folder is removed. It always acts on the OS filesystem.
+func CreateTemp(fsys fs.FS, opts ...Option) (dir string, cleanup func() error, err error) {
+	dir, err = os.MkdirTemp("", "rebed-*")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() error { return os.RemoveAll(dir) }
+	err = CreateTo(fsys, dir, append(opts[:len(opts):len(opts)], WithWriteFS(osFS{}))...)
+	if err != nil {
+		return "", nil, errors.Join(err, cleanup())
+	}
+	return dir, cleanup, nil
+}
+
+// ExtractFile copies the single embedded file name to the same relative
+// path inside dest, creating its parent folders, without walking fsys.
+// It fails if name does not exist in fsys or is a folder. The file
+// related options apply as with CreateTo; filters do not.
+func ExtractFile(fsys fs.FS, name, dest string, opts ...Option) error {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return &fs.PathError{Op: "extract", Path: name, Err: errIsDir}
+	}
+	cfg := newConfig(opts)
+	if err := cfg.sumsUnsupported("ExtractFile"); err != nil {
+		return err
+	}
+	if cfg.rename != nil && cfg.rename(name) == "" {
+		return nil // skipped by rename.
+	}
+	dst, err := cfg.fileDst(dest, name)
+	if err != nil {
+		return &PathError{Path: name, Err: err}
+	}
+	err = cfg.wfs.MkdirAll(filepath.Dir(dst), cfg.dirPerm)
+	if err != nil {
+		return err
+	}
+	err = cfg.writeFile(fsys, name, dst, fs.FileInfoToDirEntry(info))
+	if err != nil {
+		return &PathError{Path: name, Err: err}
+	}
+	return nil
+}
+
+// CreateSubtree is like CreateTo but only extracts the contents of the
+// embedded folder subpath, which is stripped from the destination paths,
+// i.e. "web/static/app.js" is written to dest/app.js for subpath "web/static".
+// Filters see paths relative to subpath. It fails if subpath is not a
+// folder in fsys.
+func CreateSubtree(fsys fs.FS, subpath, dest string, opts ...Option) error {
+	sub, err := Sub(fsys, subpath)
+	if err != nil {
+		return err
+	}
+	return CreateTo(sub, dest, opts...)
+}
+
+// Sub is like fs.Sub but first checks dir is a folder of fsys,
+// failing with an error naming it which wraps fs.ErrNotExist if it is
+// missing, rather than returning an fs.FS in which every Open fails.
+// If dir is a file the error is an *fs.PathError.
+func Sub(fsys fs.FS, dir string) (fs.FS, error) {
+	info, err := fs.Stat(fsys, dir)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("rebed: folder %q not found in fs.FS: %w", dir, err)
+	case err != nil:
+		return nil, err
+	case !info.IsDir():
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: errNotDir}
+	}
+	return fs.Sub(fsys, dir)
+}
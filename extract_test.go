@@ -0,0 +1,225 @@
+package rebed_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func TestExtract(t *testing.T) {
+	for mode, want := range map[rebed.Mode]map[string]int64{
+		rebed.ModeTree:   {"testFS/folder/subfolder": -1},
+		rebed.ModeTouch:  {"testFS/file": 0, "testFS/folder/fileInfolder": 0},
+		rebed.ModeCreate: {"testFS/file": 5, "testFS/folder/fileInfolder": 12},
+		rebed.ModePatch:  {"testFS/file": 0},
+	} {
+		dest := t.TempDir()
+		err := rebed.Extract(testFS, dest, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, size := range want {
+			info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name)))
+			if err != nil {
+				t.Fatalf("%v: %v", mode, err)
+			}
+			if size < 0 && !info.IsDir() || size >= 0 && info.Size() != size {
+				t.Errorf("%v: %q: got size %d, want %d", mode, name, info.Size(), size)
+			}
+		}
+		if mode == rebed.ModeTree {
+			if _, err := os.Stat(filepath.Join(dest, "testFS", "file")); !os.IsNotExist(err) {
+				t.Errorf("expected tree mode not to create files, got %v", err)
+			}
+		}
+	}
+	if err := rebed.Extract(testFS, t.TempDir(), rebed.Mode(-1)); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestExtractMixed(t *testing.T) {
+	dest := t.TempDir()
+	modes := map[string]rebed.Mode{
+		"testFS/file":                             rebed.ModeCreate,
+		"testFS/folder/fileInfolder":              rebed.ModeTouch,
+		"testFS/folder/subfolder/fileinsubfolder": rebed.ModeTree,
+	}
+	err := rebed.ExtractMixed(testFS, dest, func(path string) rebed.Mode { return modes[path] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int64{
+		"testFS/file":                5,
+		"testFS/folder/fileInfolder": 0,
+		"testFS/folder/subfolder":    -1,
+	} {
+		info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size < 0 && !info.IsDir() || size >= 0 && info.Size() != size {
+			t.Errorf("%q: got size %d, want %d", name, info.Size(), size)
+		}
+	}
+	_, err = os.Stat(filepath.Join(dest, "testFS", "folder", "subfolder", "fileinsubfolder"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected file decided ModeTree skipped, got %v", err)
+	}
+	err = rebed.ExtractMixed(testFS, dest, func(string) rebed.Mode { return rebed.Mode(-1) })
+	if err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestExtractFile(t *testing.T) {
+	dest := t.TempDir()
+	const name = "testFS/folder/subfolder/fileinsubfolder"
+	err := rebed.ExtractFile(testFS, name, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := testFS.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("content mismatch, got %q, want %q", got, want)
+	}
+	entries, err := os.ReadDir(filepath.Join(dest, "testFS", "folder"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the requested file extracted, got %d entries", len(entries))
+	}
+
+	err = rebed.ExtractFile(testFS, "testFS/folder", dest)
+	var perr *fs.PathError
+	if !errors.As(err, &perr) || perr.Path != "testFS/folder" {
+		t.Errorf("expected error extracting a folder, got %v", err)
+	}
+	err = rebed.ExtractFile(testFS, "testFS/nope", dest)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected %v, got %v", fs.ErrNotExist, err)
+	}
+
+	// edited files are kept by the overwrite policy and reported.
+	edited := filepath.Join(dest, filepath.FromSlash(name))
+	err = os.WriteFile(edited, []byte("edited"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	err = rebed.ExtractFile(testFS, name, dest,
+		rebed.WithOverwritePolicy(func(string, fs.FileInfo, fs.FileInfo) rebed.Decision { return rebed.Skip }),
+		rebed.WithHook(func(_ string, _ fs.DirEntry, action string) { actions = append(actions, action) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = os.ReadFile(edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "edited" || len(actions) != 1 || actions[0] != "skip" {
+		t.Errorf("expected edited file skipped, got %q and actions %q", got, actions)
+	}
+
+	err = rebed.ExtractFile(testFS, name, dest, rebed.WithRename(func(string) string { return "" }))
+	if err != nil {
+		t.Errorf("expected file skipped by rename, got %v", err)
+	}
+}
+
+func TestCreateSubtree(t *testing.T) {
+	dest := t.TempDir()
+	err := rebed.CreateSubtree(testFS, "testFS/folder", dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"fileInfolder", "subfolder/fileinsubfolder"} {
+		want, err := testFS.ReadFile("testFS/folder/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%q: content mismatch, got %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dest, "testFS")); !os.IsNotExist(err) {
+		t.Errorf("expected subpath to be stripped, got %v", err)
+	}
+
+	err = rebed.CreateSubtree(testFS, "testFS/file", dest)
+	var perr *fs.PathError
+	if !errors.As(err, &perr) || perr.Path != "testFS/file" {
+		t.Errorf("expected error for a file subpath, got %v", err)
+	}
+	err = rebed.CreateSubtree(testFS, "nope", dest)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected %v, got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestSub(t *testing.T) {
+	sub, err := rebed.Sub(testFS, "testFS/folder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fstest.TestFS(sub, "fileInfolder", "subfolder/fileinsubfolder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = rebed.Sub(testFS, "testFS/fodler")
+	if !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), `"testFS/fodler"`) {
+		t.Errorf("expected error naming missing folder, got %v", err)
+	}
+	_, err = rebed.Sub(testFS, "testFS/file")
+	var perr *fs.PathError
+	if !errors.As(err, &perr) || perr.Path != "testFS/file" {
+		t.Errorf("expected error for a file, got %v", err)
+	}
+}
+
+func TestCreateTemp(t *testing.T) {
+	dir, cleanup, err := rebed.CreateTemp(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFS(t, dir)
+	err = cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected %q removed, got %v", dir, err)
+	}
+
+	var made string
+	_, _, err = rebed.CreateTemp(failReadFS{FS: testFS, name: "testFS/folder/fileInfolder"},
+		rebed.WithHook(func(path string, _ fs.DirEntry, action string) {
+			if made == "" {
+				made = path
+			}
+		}))
+	if !errors.Is(err, errRead) {
+		t.Fatalf("expected %v, got %v", errRead, err)
+	}
+	if _, err := os.Stat(filepath.Dir(made)); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected temporary folder removed on error, got %v", err)
+	}
+}
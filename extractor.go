@@ -0,0 +1,46 @@
+package rebed
+
+import (
+	"io/fs"
+	"path"
+)
+
+// Extractor extracts the same fs.FS repeatedly, i.e. from a server
+// populating a new folder per request. The directory entries of the fs.FS
+// are read and sorted once by NewExtractor and reused by every extraction.
+// It is safe for concurrent use.
+type Extractor struct {
+	fsys cachedFS
+	opts []Option
+	err  error
+}
+
+// NewExtractor walks fsys and returns an Extractor extracting it
+// with opts. An error reading fsys is returned by every ExtractTo.
+func NewExtractor(fsys fs.FS, opts ...Option) *Extractor {
+	dirs, err := Layout(fsys)
+	return &Extractor{fsys: cachedFS{FS: fsys, dirs: dirs}, opts: opts, err: err}
+}
+
+// ExtractTo is like CreateTo with the fs.FS and options
+// passed to NewExtractor.
+func (e *Extractor) ExtractTo(dest string) error {
+	if e.err != nil {
+		return e.err
+	}
+	return CreateTo(e.fsys, dest, e.opts...)
+}
+
+// cachedFS serves the directory entries read by NewExtractor.
+type cachedFS struct {
+	fs.FS
+	dirs map[string][]fs.DirEntry
+}
+
+func (c cachedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, ok := c.dirs[path.Clean(name)]
+	if !ok {
+		return fs.ReadDir(c.FS, name)
+	}
+	return append([]fs.DirEntry(nil), entries...), nil
+}
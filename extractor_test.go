@@ -0,0 +1,35 @@
+package rebed_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/soypat/rebed"
+)
+
+func TestExtractor(t *testing.T) {
+	e := rebed.NewExtractor(testFS)
+	for i := 0; i < 2; i++ {
+		dest := t.TempDir()
+		err := e.ExtractTo(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertMatchesFS(t, dest)
+	}
+	e = rebed.NewExtractor(testFS, rebed.WithFilter(func(path string, _ fs.DirEntry) bool {
+		return path == "testFS/file"
+	}))
+	dest := t.TempDir()
+	err := e.ExtractTo(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(filepath.Join(dest, "testFS", "folder"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected options applied, got folder: %v", err)
+	}
+}
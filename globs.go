@@ -0,0 +1,72 @@
+package rebed
+
+import (
+	"path"
+	"strings"
+)
+
+// Globs selects files by their slash separated path in the embedded
+// filesystem. Patterns have path.Match syntax with the addition of
+// "**" as a whole path element, which matches zero or more elements,
+// i.e. "**/*.tmpl" matches "a.tmpl" and "web/views/b.tmpl".
+type Globs struct {
+	// Include lists the patterns of files to select.
+	// If empty every file is selected.
+	Include []string
+	// Exclude lists the patterns of files to leave out.
+	// Exclusions win over inclusions.
+	Exclude []string
+}
+
+// Match reports whether name is selected by g. The only possible
+// returned error is path.ErrBadPattern, when a pattern is malformed.
+func (g Globs) Match(name string) (bool, error) {
+	for _, pattern := range g.Exclude {
+		excluded, err := matchGlob(pattern, name)
+		if err != nil || excluded {
+			return false, err
+		}
+	}
+	if len(g.Include) == 0 {
+		return true, nil
+	}
+	for _, pattern := range g.Include {
+		included, err := matchGlob(pattern, name)
+		if err != nil || included {
+			return included, err
+		}
+	}
+	return false, nil
+}
+
+// matchGlob is like path.Match but supports "**" path elements.
+func matchGlob(pattern, name string) (bool, error) {
+	// path.Match fully validates the pattern even when there is no match.
+	if _, err := path.Match(pattern, ""); err != nil {
+		return false, err
+	}
+	return matchElems(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchElems(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				matched, err := matchElems(pattern[1:], name[i:])
+				if err != nil || matched {
+					return matched, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, nil
+		}
+		matched, err := path.Match(pattern[0], name[0])
+		if err != nil || !matched {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
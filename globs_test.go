@@ -0,0 +1,71 @@
+package rebed_test
+
+import (
+	"io/fs"
+	"path"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func TestGlobsMatch(t *testing.T) {
+	globs := rebed.Globs{
+		Include: []string{"**/*.tmpl"},
+		Exclude: []string{"node_modules/**"},
+	}
+	for name, want := range map[string]bool{
+		"a.tmpl":                  true,
+		"web/views/b.tmpl":        true,
+		"web/c.txt":               false,
+		"node_modules/d.tmpl":     false,
+		"node_modules/pkg/e.tmpl": false,
+		"web/node_modules/f.tmpl": true,
+	} {
+		got, err := globs.Match(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%q: got match %v, want %v", name, got, want)
+		}
+	}
+	_, err := rebed.Globs{Include: []string{"**/[a-"}}.Match("a")
+	if err != path.ErrBadPattern {
+		t.Errorf("expected %v, got %v", path.ErrBadPattern, err)
+	}
+}
+
+func TestWithGlobs(t *testing.T) {
+	mfs := fstest.MapFS{
+		"a.tmpl":                  &fstest.MapFile{Data: []byte("a")},
+		"web/b.tmpl":              &fstest.MapFile{Data: []byte("b")},
+		"web/c.txt":               &fstest.MapFile{Data: []byte("c")},
+		"node_modules/pkg/d.tmpl": &fstest.MapFile{Data: []byte("d")},
+		"node_modules/e.txt":      &fstest.MapFile{Data: []byte("e")},
+	}
+	globs := rebed.WithGlobs(rebed.Globs{
+		Include: []string{"**/*.tmpl"},
+		Exclude: []string{"node_modules/**"},
+	})
+	for _, extract := range []func(fs.FS, string, ...rebed.Option) error{rebed.CreateTo, rebed.PatchTo} {
+		dest := t.TempDir()
+		err := extract(mfs, dest, globs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := rebed.Diff(mfs, dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantAdded := []string{"node_modules", "node_modules/e.txt", "node_modules/pkg", "node_modules/pkg/d.tmpl", "web/c.txt"}
+		if !reflect.DeepEqual(got.Added, wantAdded) || len(got.Removed) != 0 {
+			t.Errorf("got missing paths %q, want %q", got.Added, wantAdded)
+		}
+	}
+	err := rebed.CreateTo(mfs, t.TempDir(), rebed.WithGlobs(rebed.Globs{Exclude: []string{"["}}))
+	if err != path.ErrBadPattern {
+		t.Errorf("expected %v, got %v", path.ErrBadPattern, err)
+	}
+}
@@ -0,0 +1,61 @@
+package rebed
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrSymlink is returned with WithSymlinkGuard when a path
+// an extraction writes to or through is a symbolic link.
+var ErrSymlink = errors.New("refusing to write through symbolic link")
+
+// WithSymlinkGuard makes extraction refuse to write through symbolic links
+// already inside dest, which could point outside it, i.e. if planted by an
+// attacker in a shared folder. Before writing a file or folder its path and
+// those of its parents inside dest are checked with Lstat and extraction
+// fails with an *fs.PathError wrapping ErrSymlink naming the first link
+// found. dest itself may be a link. The links WithSymlinks writes are
+// replaced as usual. The check needs the WriteFS to have an Lstat method
+// like os.Lstat; without one extraction fails with errors.ErrUnsupported.
+func WithSymlinkGuard() Option {
+	return func(c *config) { c.symlinkGuard = true }
+}
+
+// checkLinks checks no path between dest, exclusive, and its
+// descendant dst is a symbolic link. dst itself is only checked
+// if final is set.
+func (c *config) checkLinks(dest, dst string, final bool) error {
+	lstater, ok := c.wfs.(interface {
+		Lstat(string) (fs.FileInfo, error)
+	})
+	if !c.symlinkGuard {
+		return nil
+	} else if !ok {
+		return unsupported("lstat", dst)
+	}
+	rel, err := filepath.Rel(dest, dst)
+	if err != nil || rel == "." {
+		return err
+	}
+	elems := strings.Split(rel, string(filepath.Separator))
+	if !final {
+		elems = elems[:len(elems)-1]
+	}
+	name := dest
+	for _, elem := range elems {
+		name = filepath.Join(name, elem)
+		info, err := lstater.Lstat(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // nothing below exists either.
+		} else if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return &fs.PathError{Op: "write", Path: name, Err: ErrSymlink}
+		}
+	}
+	return nil
+}
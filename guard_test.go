@@ -0,0 +1,149 @@
+package rebed_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/soypat/rebed"
+)
+
+func TestSymlinkGuard(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on windows")
+	}
+	outside := t.TempDir()
+	victim := filepath.Join(outside, "victim")
+	err := os.WriteFile(victim, []byte("precious"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, link := range map[string]string{
+		"file":   "testFS/file",
+		"folder": "testFS/folder",
+	} {
+		dest := t.TempDir()
+		err = os.MkdirAll(filepath.Join(dest, "testFS"), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		target := victim
+		if name == "folder" {
+			target = outside
+		}
+		linkpath := filepath.Join(dest, filepath.FromSlash(link))
+		err = os.Symlink(target, linkpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = rebed.CreateTo(testFS, dest, rebed.WithSymlinkGuard())
+		var perr *fs.PathError
+		if !errors.Is(err, rebed.ErrSymlink) || !errors.As(err, &perr) || perr.Path != linkpath {
+			t.Errorf("%s: expected %v naming %q, got %v", name, rebed.ErrSymlink, linkpath, err)
+		}
+		got, err := os.ReadFile(victim)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "precious" {
+			t.Errorf("%s: file outside dest overwritten with %q", name, got)
+		}
+	}
+
+	// dest itself may be a link.
+	link := filepath.Join(t.TempDir(), "link")
+	err = os.Symlink(t.TempDir(), link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.CreateTo(testFS, link, rebed.WithSymlinkGuard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFS(t, link)
+}
+
+func TestSymlinkGuardUnsupported(t *testing.T) {
+	// a WriteFS without Lstat can't be checked.
+	err := rebed.CreateTo(testFS, ".", rebed.WithWriteFS(&rebed.MemFS{}), rebed.WithSymlinkGuard())
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("expected %v, got %v", errors.ErrUnsupported, err)
+	}
+}
+
+func TestSymlinkGuardTouch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on windows")
+	}
+	extract := map[string]func(fs.FS, string, ...rebed.Option) error{
+		"Touch": rebed.TouchTo,
+		"Patch": rebed.PatchTo,
+		"Tree":  rebed.TreeTo,
+	}
+	for name, extract := range extract {
+		outside := t.TempDir()
+		dest := t.TempDir()
+		err := os.MkdirAll(filepath.Join(dest, "testFS"), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// a dangling link is followed when creating a file.
+		link, target := "testFS/file", filepath.Join(outside, "planted")
+		if name == "Tree" {
+			link, target = "testFS/folder", outside
+		}
+		linkpath := filepath.Join(dest, filepath.FromSlash(link))
+		err = os.Symlink(target, linkpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = extract(testFS, dest, rebed.WithSymlinkGuard())
+		var perr *fs.PathError
+		if !errors.Is(err, rebed.ErrSymlink) || !errors.As(err, &perr) || perr.Path != linkpath {
+			t.Errorf("%s: expected %v naming %q, got %v", name, rebed.ErrSymlink, linkpath, err)
+		}
+		entries, err := os.ReadDir(outside)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("%s: created %q outside dest", name, entries[0].Name())
+		}
+	}
+}
+
+func TestSymlinkGuardTouchReset(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on windows")
+	}
+	victim := filepath.Join(t.TempDir(), "victim")
+	err := os.WriteFile(victim, []byte("precious"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := t.TempDir()
+	err = os.MkdirAll(filepath.Join(dest, "testFS"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	linkpath := filepath.Join(dest, "testFS", "file")
+	err = os.Symlink(victim, linkpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.TouchReset(testFS, dest, rebed.WithSymlinkGuard())
+	var perr *fs.PathError
+	if !errors.Is(err, rebed.ErrSymlink) || !errors.As(err, &perr) || perr.Path != linkpath {
+		t.Errorf("expected %v naming %q, got %v", rebed.ErrSymlink, linkpath, err)
+	}
+	got, err := os.ReadFile(victim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "precious" {
+		t.Errorf("file outside dest truncated to %q", got)
+	}
+}
@@ -0,0 +1,30 @@
+package rebed
+
+import (
+	"io/fs"
+	"net/http"
+)
+
+// HTTPFS returns an http.FileSystem serving the contents of the folder
+// subpath of fsys, "." for its root, without extracting them, i.e.
+//
+//	http.Handle("/", http.FileServer(rebed.HTTPFS(content, "static")))
+//
+// To serve the files users edited on disk in preference to the embedded
+// ones pass an Overlay, HTTPFS(Overlay(content, dir), "static"), where dir
+// is the folder the embedded files were extracted to. If subpath is not
+// valid every Open fails with the error from fs.Sub.
+func HTTPFS(fsys fs.FS, subpath string) http.FileSystem {
+	sub, err := fs.Sub(fsys, subpath)
+	if err != nil {
+		return errHTTPFS{err: err}
+	}
+	return http.FS(sub)
+}
+
+// errHTTPFS fails to open any file with err.
+type errHTTPFS struct {
+	err error
+}
+
+func (e errHTTPFS) Open(string) (http.File, error) { return nil, e.err }
@@ -0,0 +1,63 @@
+package rebed_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func TestHTTPFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"static/index.html": {Data: []byte("<html>")},
+		"static/app.js":     {Data: []byte("default")},
+	}
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "static"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "static", "app.js"), []byte("edited"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, test := range map[string]struct {
+		fs   http.FileSystem
+		want map[string]string
+	}{
+		"embedded": {
+			fs:   rebed.HTTPFS(fsys, "static"),
+			want: map[string]string{"/index.html": "<html>", "/app.js": "default"},
+		},
+		"overlay": {
+			fs:   rebed.HTTPFS(rebed.Overlay(fsys, dir), "static"),
+			want: map[string]string{"/index.html": "<html>", "/app.js": "edited"},
+		},
+	} {
+		srv := httptest.NewServer(http.FileServer(test.fs))
+		for path, want := range test.want {
+			resp, err := http.Get(srv.URL + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusOK || string(b) != want {
+				t.Errorf("%s: %s: got %d %q, want %q", name, path, resp.StatusCode, b, want)
+			}
+		}
+		srv.Close()
+	}
+
+	if _, err := rebed.HTTPFS(fsys, "/static").Open("/index.html"); err == nil {
+		t.Error("expected error for invalid subpath")
+	}
+}
@@ -0,0 +1,99 @@
+package rebed
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// WithIgnoreFile makes extraction skip the entries of fsys matched by the
+// file name in fsys, say ".embedignore", which lists patterns in the syntax
+// of a .gitignore file, so exclusions may be kept along with the assets:
+//
+//	# comments and blank lines are ignored.
+//	*.tmp        skips files named so in any folder
+//	/docs/*.md   patterns with a slash are relative to the root of fsys
+//	build/       a trailing slash only matches folders and all they hold
+//	!keep.tmp    negates a previous pattern
+//
+// Patterns have the syntax of Globs, "**" included. As with git a file
+// inside an ignored folder can't be negated back in. The ignore file is
+// extracted like any other file unless it lists itself. Extraction fails
+// if it is missing or holds a malformed pattern.
+func WithIgnoreFile(name string) Option {
+	return func(c *config) { c.ignoreFile = name }
+}
+
+// ignoreRule is a pattern of an ignore file.
+type ignoreRule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// readIgnoreFile parses the ignore file name of fsys.
+func readIgnoreFile(fsys fs.FS, name string) ([]ignoreRule, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	var rules []ignoreRule
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		var rule ignoreRule
+		if line[0] == '!' {
+			rule.negate = true
+			line = line[1:]
+		} else if line[0] == '\\' {
+			line = line[1:] // escapes a leading '#' or '!'.
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+		if _, err := path.Match(line, ""); err != nil || line == "" {
+			return nil, fmt.Errorf("rebed: %s:%d: %w", name, i+1, path.ErrBadPattern)
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// ignored reports whether the file or folder name is ignored by rules,
+// not taking its parent folders into account.
+func ignored(rules []ignoreRule, name string, isDir bool) bool {
+	ignore := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir || rule.negate != ignore {
+			continue // can't change the result.
+		}
+		// patterns are validated when read.
+		if matched, _ := matchGlob(rule.pattern, name); matched {
+			ignore = !rule.negate
+		}
+	}
+	return ignore
+}
+
+// ignoreFilter returns a WithFilter callback rejecting
+// the files ignored by rules or inside an ignored folder.
+func ignoreFilter(rules []ignoreRule) func(name string, de fs.DirEntry) bool {
+	return func(name string, de fs.DirEntry) bool {
+		for i := 0; i < len(name); i++ {
+			if name[i] == '/' && ignored(rules, name[:i], true) {
+				return false
+			}
+		}
+		return !ignored(rules, name, de.IsDir())
+	}
+}
@@ -0,0 +1,76 @@
+package rebed_test
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func TestIgnoreFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		".embedignore": {Data: []byte(`# scratch files
+*.tmp
+!keep.tmp
+build/
+!build/out.js
+/docs/*.md
+.embedignore
+`)},
+		"a.tmp":            {},
+		"sub/b.tmp":        {},
+		"sub/keep.tmp":     {},
+		"build/out.js":     {},
+		"web/build/app.js": {},
+		"web/build.js":     {},
+		"docs/readme.md":   {},
+		"web/docs/api.md":  {},
+		"index.html":       {},
+	}
+	var got []string
+	var mem rebed.MemFS
+	err := rebed.CreateTo(fsys, ".", rebed.WithWriteFS(&mem), rebed.WithIgnoreFile(".embedignore"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fs.WalkDir(mem.MapFS(), ".", func(name string, de fs.DirEntry, err error) error {
+		if err == nil && !de.IsDir() {
+			got = append(got, name)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"index.html", "sub/keep.tmp", "web/build.js", "web/docs/api.md"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got files %q, want %q", got, want)
+	}
+
+	err = rebed.CreateTo(fsys, ".", rebed.WithWriteFS(&rebed.MemFS{}), rebed.WithIgnoreFile("missing"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected %v for missing ignore file, got %v", fs.ErrNotExist, err)
+	}
+	fsys["bad"] = &fstest.MapFile{Data: []byte("ok\n[\n")}
+	err = rebed.CreateTo(fsys, ".", rebed.WithWriteFS(&rebed.MemFS{}), rebed.WithIgnoreFile("bad"))
+	if !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("expected %v, got %v", path.ErrBadPattern, err)
+	}
+}
+
+func TestIgnoreFileCaseCheck(t *testing.T) {
+	fsys := fstest.MapFS{
+		".embedignore": {Data: []byte("README.md\n")},
+		"readme.md":    {},
+		"README.md":    {},
+	}
+	var mem rebed.MemFS
+	err := rebed.CreateTo(fsys, ".", rebed.WithWriteFS(&mem), rebed.WithIgnoreFile(".embedignore"), rebed.WithCaseCheck())
+	if err != nil {
+		t.Errorf("expected ignored paths not to collide, got %v", err)
+	}
+}
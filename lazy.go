@@ -0,0 +1,53 @@
+package rebed
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LazyFS returns an fs.FS serving the files of fsys from cacheDir on disk,
+// extracting each one there the first time it is opened, i.e. for apps only
+// reading a few of thousands of embedded files through tools needing them on
+// disk. Files are written atomically so concurrent opens never see them
+// partially written. Files already in cacheDir are served as they are, so
+// cacheDir should be specific to the version of fsys. Folders are served
+// from fsys.
+func LazyFS(fsys fs.FS, cacheDir string) fs.FS {
+	return lazyFS{fsys: fsys, dir: cacheDir}
+}
+
+type lazyFS struct {
+	fsys fs.FS
+	dir  string
+}
+
+func (l lazyFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	cached := filepath.Join(l.dir, filepath.FromSlash(name))
+	f, err := os.Open(cached)
+	if err == nil {
+		info, err := f.Stat()
+		if err == nil && !info.IsDir() {
+			return f, nil
+		}
+		f.Close()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	info, err := fs.Stat(l.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return l.fsys.Open(name)
+	}
+	err = ExtractFile(l.fsys, name, l.dir, WithAtomicWrites())
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(cached)
+}
@@ -0,0 +1,61 @@
+package rebed_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func TestLazyFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"tools/a.sh": {Data: []byte("echo a")},
+		"tools/b.sh": {Data: []byte("echo b")},
+	}
+	cache := t.TempDir()
+	lfs := rebed.LazyFS(fsys, cache)
+	got, err := fs.ReadFile(lfs, "tools/a.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "echo a" {
+		t.Errorf("got %q", got)
+	}
+	cached := filepath.Join(cache, "tools", "a.sh")
+	if _, err := os.Stat(cached); err != nil {
+		t.Errorf("expected opened file extracted: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cache, "tools", "b.sh")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected file never opened not extracted, got %v", err)
+	}
+
+	// later opens are served from disk.
+	err = os.WriteFile(cached, []byte("edited"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = fs.ReadFile(lfs, "tools/a.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "edited" {
+		t.Errorf("expected cached file, got %q", got)
+	}
+
+	entries, err := fs.ReadDir(lfs, "tools")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected folders served from fsys, got %d entries", len(entries))
+	}
+	for _, name := range []string{"missing", "../tools/a.sh"} {
+		if _, err := lfs.Open(name); err == nil {
+			t.Errorf("%q: expected error", name)
+		}
+	}
+}
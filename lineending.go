@@ -0,0 +1,79 @@
+package rebed
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+)
+
+// LineEnding is the line terminator WithLineEnding converts text files to.
+type LineEnding int
+
+const (
+	// LF terminates lines with "\n".
+	LF LineEnding = iota
+	// CRLF terminates lines with "\r\n".
+	CRLF
+	// Native is CRLF on Windows and LF elsewhere.
+	Native
+)
+
+// WithLineEnding makes Create convert the line endings of the embedded
+// files selected by text to ending as they are copied, whatever the
+// endings they were embedded with, even mixed ones. Other files are copied
+// byte for byte, so text should not select binary files.
+func WithLineEnding(ending LineEnding, text Globs) Option {
+	if ending == Native {
+		ending = LF
+		if runtime.GOOS == "windows" {
+			ending = CRLF
+		}
+	}
+	return func(c *config) {
+		c.lineEnding = ending
+		c.text = &text
+	}
+}
+
+// lineWriter converts the line endings written to w.
+type lineWriter struct {
+	w      io.Writer
+	crlf   bool
+	lastCR bool // whether the last byte written was '\r'.
+}
+
+func (lw *lineWriter) Write(b []byte) (int, error) {
+	var buf bytes.Buffer
+	for _, c := range b {
+		if lw.crlf {
+			if c == '\n' && !lw.lastCR {
+				buf.WriteByte('\r')
+			}
+			buf.WriteByte(c)
+		} else {
+			if lw.lastCR && c != '\n' {
+				// the '\r' held back was not part of a line ending.
+				buf.WriteByte('\r')
+			}
+			if c != '\r' {
+				buf.WriteByte(c)
+			}
+		}
+		lw.lastCR = c == '\r'
+	}
+	_, err := lw.w.Write(buf.Bytes())
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// flush writes the '\r' held back at the end of an LF file, if any.
+func (lw *lineWriter) flush() error {
+	if lw.crlf || !lw.lastCR {
+		return nil
+	}
+	lw.lastCR = false
+	_, err := lw.w.Write([]byte{'\r'})
+	return err
+}
@@ -0,0 +1,86 @@
+package rebed
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// WithLogger makes extraction log through l: every path acted on at
+// debug level with its path on disk, the action as passed to the hook of
+// WithHook and, for files, their embedded size in bytes, and a summary at
+// info level when the walk starts and finishes, or at error level if it
+// failed. Functions which only read fsys, such as Stat, Missing, WriteTar
+// and ToMapFS, are not logged. Nothing is logged with a nil l.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = nil
+		if l != nil {
+			c.logger = &logger{l: l}
+		}
+	}
+}
+
+// logger logs an extraction and tallies its summary.
+// It is safe for concurrent use.
+type logger struct {
+	l     *slog.Logger
+	start time.Time
+
+	mu      sync.Mutex
+	files   int
+	dirs    int
+	skipped int
+	bytes   int64
+}
+
+// started logs the start of a walk.
+func (lg *logger) started() {
+	lg.start = time.Now()
+	lg.l.LogAttrs(context.Background(), slog.LevelInfo, "rebed: extraction started")
+}
+
+// visit logs action on the path dst for the embedded entry de.
+func (lg *logger) visit(dst string, de fs.DirEntry, action string) {
+	attrs := []slog.Attr{slog.String("path", dst), slog.String("action", action)}
+	var size int64
+	if !de.IsDir() {
+		if info, err := de.Info(); err == nil {
+			size = info.Size()
+			attrs = append(attrs, slog.Int64("bytes", size))
+		}
+	}
+	lg.mu.Lock()
+	switch {
+	case action == "skip":
+		lg.skipped++
+	case de.IsDir():
+		lg.dirs++
+	default:
+		lg.files++
+		lg.bytes += size
+	}
+	lg.mu.Unlock()
+	lg.l.LogAttrs(context.Background(), slog.LevelDebug, "rebed: "+action, attrs...)
+}
+
+// finished logs the summary of a walk which ended with err.
+func (lg *logger) finished(err error) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	attrs := []slog.Attr{
+		slog.Int("files", lg.files),
+		slog.Int("dirs", lg.dirs),
+		slog.Int("skipped", lg.skipped),
+		slog.Int64("bytes", lg.bytes),
+		slog.Duration("elapsed", time.Since(lg.start)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("err", err))
+		lg.l.LogAttrs(context.Background(), slog.LevelError, "rebed: extraction failed", attrs...)
+		return
+	}
+	lg.l.LogAttrs(context.Background(), slog.LevelInfo, "rebed: extraction finished", attrs...)
+}
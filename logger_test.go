@@ -0,0 +1,93 @@
+package rebed_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/soypat/rebed"
+)
+
+func TestLogger(t *testing.T) {
+	for name, create := range map[string]func(dest string, opts ...rebed.Option) error{
+		"CreateTo": func(dest string, opts ...rebed.Option) error { return rebed.CreateTo(testFS, dest, opts...) },
+		"CreateParallel": func(dest string, opts ...rebed.Option) error {
+			return rebed.CreateParallel(testFS, dest, 2, opts...)
+		},
+	} {
+		var buf bytes.Buffer
+		l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		err := create(t.TempDir(), rebed.WithLogger(l))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 8 {
+			t.Fatalf("%s: expected 8 lines logged, got %d:\n%s", name, len(lines), buf.String())
+		}
+		if !strings.Contains(lines[0], "level=INFO") || !strings.Contains(lines[0], "extraction started") {
+			t.Errorf("%s: got first line %q", name, lines[0])
+		}
+		last := lines[len(lines)-1]
+		for _, want := range []string{"level=INFO", "extraction finished", "files=3", "dirs=3", "bytes="} {
+			if !strings.Contains(last, want) {
+				t.Errorf("%s: expected %q in summary %q", name, want, last)
+			}
+		}
+		if !strings.Contains(buf.String(), "level=DEBUG msg=\"rebed: create\" path=") {
+			t.Errorf("%s: expected per file debug lines, got:\n%s", name, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, nil))
+	err := rebed.CreateTo(failReadFS{FS: testFS, name: "testFS/file"}, t.TempDir(), rebed.WithLogger(l))
+	if !errors.Is(err, errRead) {
+		t.Fatalf("expected %v, got %v", errRead, err)
+	}
+	if !strings.Contains(buf.String(), "level=ERROR msg=\"rebed: extraction failed\"") {
+		t.Errorf("expected failure logged, got:\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "DEBUG") {
+		t.Errorf("expected debug lines filtered out by the handler, got:\n%s", buf.String())
+	}
+}
+
+func TestLoggerActions(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	dest := t.TempDir()
+	err := rebed.CreateTo(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.CreateTo(testFS, dest, rebed.WithLogger(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(buf.String(), `msg="rebed: overwrite"`); got != 3 || strings.Contains(buf.String(), `msg="rebed: create"`) {
+		t.Errorf("expected 3 files logged as overwritten, got %d:\n%s", got, buf.String())
+	}
+
+	// functions which only read fsys are not logged.
+	buf.Reset()
+	_, _, _, err = rebed.Stat(testFS, rebed.WithLogger(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.WriteTar(testFS, io.Discard, rebed.WithLogger(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing logged, got:\n%s", buf.String())
+	}
+
+	err = rebed.CreateTo(testFS, t.TempDir(), rebed.WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
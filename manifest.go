@@ -0,0 +1,210 @@
+package rebed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+)
+
+// FileInfo describes an entry of an embedded filesystem in a manifest.
+type FileInfo struct {
+	// Path is the slash separated path of the entry.
+	Path  string
+	IsDir bool
+	// Size of the file in bytes. Zero for folders.
+	Size int64
+	// SHA256 is the hex encoded SHA-256 checksum of the file's
+	// contents. Empty for folders.
+	SHA256 string
+}
+
+// Manifest walks fsys and describes every file and folder in it, in the
+// order of Walk. Files are hashed as they are read, not loaded into memory.
+// The result can be stored to verify a release or drive CreateManifest.
+func Manifest(fsys fs.FS) ([]FileInfo, error) {
+	var manifest []FileInfo
+	err := Walk(fsys, ".", func(dirpath string, de fs.DirEntry) error {
+		info := FileInfo{Path: path.Join(dirpath, de.Name()), IsDir: de.IsDir()}
+		if !info.IsDir {
+			f, err := fsys.Open(info.Path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			h := sha256.New()
+			err = copyBuffer(h, f)
+			if err != nil {
+				return err
+			}
+			stat, err := f.Stat()
+			if err != nil {
+				return err
+			}
+			info.Size = stat.Size()
+			info.SHA256 = hex.EncodeToString(h.Sum(nil))
+		}
+		manifest = append(manifest, info)
+		return nil
+	})
+	return manifest, err
+}
+
+// CreateManifest is like CreateTo but only extracts the files listed in
+// paths along with the folders holding them. A listed folder extracts its
+// entire contents. Every path is checked to exist in fsys before anything
+// is written; the returned error names all missing paths and wraps
+// fs.ErrNotExist.
+func CreateManifest(fsys fs.FS, dest string, paths []string, opts ...Option) error {
+	only, err := onlyPaths(fsys, paths)
+	if err != nil {
+		return err
+	}
+	return CreateTo(fsys, dest, append(opts[:len(opts):len(opts)], only)...)
+}
+
+// Restore resets the files listed in paths inside dest to their embedded
+// contents, i.e. for a "reset to defaults" button. A listed folder restores
+// its entire contents. Only files whose contents differ are written, so
+// files which were not edited keep their modification time. Every path is
+// checked to exist in fsys before anything is written as by CreateManifest.
+func Restore(fsys fs.FS, dest string, paths []string, opts ...Option) error {
+	only, err := onlyPaths(fsys, paths)
+	if err != nil {
+		return err
+	}
+	_, err = CreateIfChanged(fsys, dest, append(opts[:len(opts):len(opts)], only)...)
+	return err
+}
+
+// onlyPaths returns a filter keeping the files listed in paths and
+// those inside the listed folders, failing if any is not in fsys.
+func onlyPaths(fsys fs.FS, paths []string) (Option, error) {
+	listed := make(map[string]bool, len(paths))
+	var missing []string
+	for _, name := range paths {
+		_, err := fs.Stat(fsys, name)
+		switch {
+		case errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid):
+			missing = append(missing, name)
+		case err != nil:
+			return nil, err
+		default:
+			listed[name] = true
+		}
+	}
+	if len(missing) != 0 {
+		return nil, fmt.Errorf("rebed: paths %q not found in fs.FS: %w", missing, fs.ErrNotExist)
+	}
+	inManifest := func(name string, _ fs.DirEntry) bool {
+		for !listed[name] {
+			if name == "." {
+				return false
+			}
+			name = path.Dir(name)
+		}
+		return true
+	}
+	return WithFilter(inManifest), nil
+}
+
+// Stat counts the files and folders of fsys and sums the size of its
+// files, i.e. to check enough disk space is available before calling
+// Create. Filter options are honored so the totals match what an
+// extraction with the same options writes.
+func Stat(fsys fs.FS, opts ...Option) (files, dirs int, totalBytes int64, err error) {
+	cfg := newConfig(opts)
+	err = cfg.read(fsys, func(dirpath string, de fs.DirEntry) error {
+		if de.IsDir() {
+			dirs++
+			return nil
+		}
+		info, err := de.Info()
+		if err != nil {
+			return err
+		}
+		files++
+		totalBytes += info.Size()
+		return nil
+	})
+	return files, dirs, totalBytes, err
+}
+
+// Dirs returns the slash separated path of every folder in fsys, sorted,
+// excluding its root. It is Tree as data rather than folders on disk.
+// See Files for the files.
+func Dirs(fsys fs.FS) ([]string, error) {
+	var dirs []string
+	err := Walk(fsys, ".", func(dirpath string, de fs.DirEntry) error {
+		if de.IsDir() {
+			dirs = append(dirs, path.Join(dirpath, de.Name()))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// Layout returns the entries of every folder of fsys, sorted by name,
+// keyed by the folder's slash separated path, "." for the root, i.e. for
+// a file browser to expand folders without reading fsys again. fsys is
+// read once, as by Walk.
+func Layout(fsys fs.FS) (map[string][]fs.DirEntry, error) {
+	dirs := make(map[string][]fs.DirEntry)
+	err := WalkDir(fsys, ".", func(name string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if de.IsDir() {
+			dirs[name] = []fs.DirEntry{} // listed even if empty.
+		}
+		if name != "." {
+			// WalkDir visits entries sorted.
+			parent := path.Dir(name)
+			dirs[parent] = append(dirs[parent], de)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dirs, nil
+}
+
+// Exists reports whether the file or folder name, a slash separated
+// path, is in fsys. Invalid paths and errors other than fs.ErrNotExist
+// are reported as missing; use fs.Stat to tell them apart.
+func Exists(fsys fs.FS, name string) bool {
+	_, err := fs.Stat(fsys, name)
+	return err == nil
+}
+
+// TopLevel returns the entries at the root of fsys sorted by name,
+// without reading any folder below it.
+func TopLevel(fsys fs.FS) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	sortEntries(entries)
+	return entries, err
+}
+
+// Files returns the slash separated path of every file in fsys, sorted.
+func Files(fsys fs.FS) ([]string, error) {
+	var files []string
+	err := Walk(fsys, ".", func(dirpath string, de fs.DirEntry) error {
+		if !de.IsDir() {
+			files = append(files, path.Join(dirpath, de.Name()))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,251 @@
+package rebed_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func TestCreateManifest(t *testing.T) {
+	dest := t.TempDir()
+	err := rebed.CreateManifest(testFS, dest, []string{"testFS/file", "testFS/folder/subfolder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := rebed.Diff(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantAdded := []string{"testFS/folder/fileInfolder"}
+	if !reflect.DeepEqual(diff.Added, wantAdded) || len(diff.Changed) != 0 {
+		t.Errorf("got diff %+v, want only %q missing", diff, wantAdded)
+	}
+
+	dest = t.TempDir()
+	err = rebed.CreateManifest(testFS, dest, []string{"testFS/file", "nope", "testFS/nope"})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected %v, got %v", fs.ErrNotExist, err)
+	}
+	if err != nil && (!strings.Contains(err.Error(), `"nope"`) || !strings.Contains(err.Error(), `"testFS/nope"`)) {
+		t.Errorf("expected error to name missing paths, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "testFS")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing extracted with missing paths, got %v", err)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	manifest, err := rebed.Manifest(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, info := range manifest {
+		paths = append(paths, info.Path)
+		if info.IsDir {
+			if info.Size != 0 || info.SHA256 != "" {
+				t.Errorf("%q: expected no size nor checksum for folder, got %+v", info.Path, info)
+			}
+			continue
+		}
+		b, err := testFS.ReadFile(info.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(b)
+		if info.SHA256 != hex.EncodeToString(sum[:]) || info.Size != int64(len(b)) {
+			t.Errorf("%q: got %+v, want size %d and checksum %x", info.Path, info, len(b), sum)
+		}
+	}
+	want := []string{"testFS", "testFS/file", "testFS/folder", "testFS/folder/fileInfolder",
+		"testFS/folder/subfolder", "testFS/folder/subfolder/fileinsubfolder"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("got paths %q, want %q", paths, want)
+	}
+}
+
+func TestStat(t *testing.T) {
+	files, dirs, totalBytes, err := rebed.Stat(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wantBytes int64
+	for _, name := range []string{"testFS/file", "testFS/folder/fileInfolder", "testFS/folder/subfolder/fileinsubfolder"} {
+		b, err := testFS.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantBytes += int64(len(b))
+	}
+	if files != 3 || dirs != 3 || totalBytes != wantBytes {
+		t.Errorf("got %d files, %d dirs, %d bytes, want 3, 3, %d", files, dirs, totalBytes, wantBytes)
+	}
+}
+
+func TestDirs(t *testing.T) {
+	dirs, err := rebed.Dirs(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"testFS", "testFS/folder", "testFS/folder/subfolder"}
+	if !reflect.DeepEqual(dirs, want) {
+		t.Errorf("got %q, want %q", dirs, want)
+	}
+	dirs, err = rebed.Dirs(fstest.MapFS{"a/b/file": {}, "a-c/file": {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"a", "a-c", "a/b"}
+	if !reflect.DeepEqual(dirs, want) {
+		t.Errorf("expected sorted paths %q, got %q", want, dirs)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	files, err := rebed.Files(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"testFS/file", "testFS/folder/fileInfolder", "testFS/folder/subfolder/fileinsubfolder"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("got %q, want %q", files, want)
+	}
+	files, err = rebed.Files(fstest.MapFS{"a/b": {}, "a-c": {}, "dir": {Mode: fs.ModeDir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"a-c", "a/b"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("expected sorted paths %q, got %q", want, files)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	layout, err := rebed.Layout(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]string)
+	for dir, entries := range layout {
+		for _, de := range entries {
+			got[dir] = append(got[dir], de.Name())
+		}
+	}
+	want := map[string][]string{
+		".":                       {"testFS"},
+		"testFS":                  {"file", "folder"},
+		"testFS/folder":           {"fileInfolder", "subfolder"},
+		"testFS/folder/subfolder": {"fileinsubfolder"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// fsys is read once.
+	counting := &readDirCounter{FS: testFS}
+	_, err = rebed.Layout(counting)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counting.calls != len(want) {
+		t.Errorf("expected %d folders read once each, got %d reads", len(want), counting.calls)
+	}
+}
+
+// readDirCounter counts the folders read from FS.
+type readDirCounter struct {
+	fs.FS
+	calls int
+}
+
+func (r *readDirCounter) ReadDir(name string) ([]fs.DirEntry, error) {
+	r.calls++
+	return fs.ReadDir(r.FS, name)
+}
+
+func TestTopLevel(t *testing.T) {
+	fsys := fstest.MapFS{
+		"z.txt":     {},
+		"a/b/c.txt": {},
+		"m/n.txt":   {},
+	}
+	entries, err := rebed.TopLevel(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, de := range entries {
+		got = append(got, de.Name())
+	}
+	if want := []string{"a", "m", "z.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExists(t *testing.T) {
+	for name, want := range map[string]bool{
+		".":                  true,
+		"testFS":             true,
+		"testFS/file":        true,
+		"testFS/folder/":     false,
+		"testFS/missing":     false,
+		"testFS/file/nested": false,
+		"/testFS":            false,
+	} {
+		if got := rebed.Exists(testFS, name); got != want {
+			t.Errorf("%q: got %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestRestore(t *testing.T) {
+	dest := t.TempDir()
+	err := rebed.CreateTo(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edits := map[string]string{
+		"testFS/file":                             "edited",
+		"testFS/folder/fileInfolder":              "edited",
+		"testFS/folder/subfolder/fileinsubfolder": "kept",
+	}
+	for name, data := range edits {
+		err = os.WriteFile(filepath.Join(dest, filepath.FromSlash(name)), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = rebed.Restore(testFS, dest, []string{"testFS/file", "testFS/folder/fileInfolder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range edits {
+		want, err := testFS.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data == "kept" {
+			want = []byte(data)
+		}
+		got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%q: got %q, want %q", name, got, want)
+		}
+	}
+	err = rebed.Restore(testFS, dest, []string{"testFS/file", "testFS/nope"})
+	if !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), "testFS/nope") {
+		t.Errorf("expected error naming missing path, got %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package rebed
+
+import (
+	"io/fs"
+	"path"
+	"testing/fstest"
+)
+
+// ToMapFS copies fsys into memory with the same paths, contents and modes.
+// It lets tests of code consuming an extracted tree run without touching
+// the disk. Filter options are honored.
+func ToMapFS(fsys fs.FS, opts ...Option) (fstest.MapFS, error) {
+	cfg := newConfig(opts)
+	mfs := make(fstest.MapFS)
+	err := cfg.read(fsys, func(dirpath string, de fs.DirEntry) error {
+		info, err := de.Info()
+		if err != nil {
+			return err
+		}
+		name := path.Join(dirpath, de.Name())
+		mf := &fstest.MapFile{Mode: info.Mode(), ModTime: info.ModTime()}
+		if !de.IsDir() {
+			mf.Data, err = fs.ReadFile(fsys, name)
+			if err != nil {
+				return err
+			}
+		}
+		mfs[name] = mf
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mfs, nil
+}
@@ -0,0 +1,31 @@
+package rebed_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func TestToMapFS(t *testing.T) {
+	mfs, err := rebed.ToMapFS(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fstest.TestFS(mfs, "testFS/file", "testFS/folder/fileInfolder", "testFS/folder/subfolder/fileinsubfolder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"testFS/file", "testFS/folder/fileInfolder", "testFS/folder/subfolder/fileinsubfolder"} {
+		want, err := testFS.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(mfs[name].Data) != string(want) {
+			t.Errorf("%q: content mismatch, got %q, want %q", name, mfs[name].Data, want)
+		}
+	}
+	if len(mfs) != 6 {
+		t.Errorf("expected 6 entries, got %d", len(mfs))
+	}
+}
@@ -0,0 +1,465 @@
+package rebed
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Option configures the behavior of the extraction functions.
+type Option func(*config)
+
+// config holds the settings applied by a set of Options.
+type config struct {
+	dest      string
+	wfs       WriteFS
+	dirPerm   os.FileMode
+	fileMode  func(path string) os.FileMode
+	atomic    bool
+	filter    func(path string, de fs.DirEntry) bool
+	globs     *Globs
+	skipEmpty bool
+	// skipEmptyDirs makes walk pass folders lazily even without filters.
+	skipEmptyDirs bool
+	progress      *progress
+	modTime       time.Time
+	// dirs holds the folders created to stamp with modTime.
+	dirs []string
+	hook func(path string, de fs.DirEntry, action string)
+
+	overwrite func(path string, embedInfo, diskInfo fs.FileInfo) Decision
+	template  *templateConfig
+	gunzip    bool
+	symlink   func(path string) (target string, isLink bool)
+	rename    func(embedPath string) (destPath string)
+	// detectExec enables WithDetectExecutable.
+	detectExec bool
+
+	checkpoint string
+
+	pruneEmptyDirs  bool
+	continueOnError bool
+	caseCheck       bool
+	symlinkGuard    bool
+	maxDepth        int
+	skipHidden      bool
+	stats           *Stats
+	lineEnding      LineEnding
+	text            *Globs
+	normalize       *Globs
+	ignoreFile      string
+	durable         bool
+	checksums       *checksums
+	gzipOutput      string
+	logger          *logger
+	// deferFinish makes the caller of walk call finish.
+	deferFinish bool
+
+	retries   int
+	backoff   time.Duration
+	transient func(error) bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{dirPerm: folderPerm, wfs: osFS{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// walk is like Walk over the root of fsys but skips the files rejected by
+// the filters and wraps the errors returned by fn in a *PathError. With a
+// filter set folders are passed to f lazily, right before the first file
+// inside them, so folders without any kept file are never passed to f.
+// With continueOnError set the errors returned by fn are joined and
+// returned once the walk is done, the contents of folders for which fn
+// failed are skipped.
+func (c *config) walk(fsys fs.FS, fn func(dirpath string, de fs.DirEntry) error) error {
+	if c.ignoreFile != "" {
+		rules, err := readIgnoreFile(fsys, c.ignoreFile)
+		if err != nil {
+			return err
+		}
+		WithFilter(ignoreFilter(rules))(c)
+		c.ignoreFile = "" // walk may be called again.
+	}
+	// after the ignore file so ignored paths can't collide.
+	if c.caseCheck {
+		if err := c.checkCase(fsys); err != nil {
+			return err
+		}
+	}
+	if c.logger != nil {
+		c.logger.started()
+	}
+	var errs []error
+	var failedDirs []string
+	f := func(dirpath string, de fs.DirEntry) error {
+		for _, dir := range failedDirs {
+			if dirpath == dir || strings.HasPrefix(dirpath, dir+"/") {
+				return nil
+			}
+		}
+		err := fn(dirpath, de)
+		if err == nil {
+			return nil
+		}
+		fullpath := path.Join(dirpath, de.Name())
+		err = &PathError{Path: fullpath, Err: err}
+		if !c.collect(err) {
+			return err
+		}
+		errs = append(errs, err)
+		if de.IsDir() {
+			failedDirs = append(failedDirs, fullpath)
+		}
+		return nil
+	}
+	err := c.walkFiltered(fsys, f)
+	if len(errs) != 0 {
+		err = errors.Join(append(errs, err)...)
+	}
+	if c.deferFinish {
+		return err
+	}
+	return c.finish(err)
+}
+
+// read is like walk for the functions which read fsys without
+// extracting it, which are not logged by WithLogger.
+func (c *config) read(fsys fs.FS, fn func(dirpath string, de fs.DirEntry) error) error {
+	c.logger = nil
+	return c.walk(fsys, fn)
+}
+
+// finish completes an extraction which ended with err: the times of its
+// folders are set and its summary logged. walk calls it unless
+// deferFinish is set, when files are written after the walk.
+func (c *config) finish(err error) error {
+	// folders are set even on error, i.e. with WithContinueOnError.
+	if serr := c.stampDirs(); err == nil {
+		err = serr
+	}
+	if c.logger != nil {
+		c.logger.finished(err)
+	}
+	return err
+}
+
+// collect reports whether err should be collected instead of
+// aborting an extraction.
+func (c *config) collect(err error) bool {
+	return c.continueOnError && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// walkFiltered is like Walk over the root of fsys but skips
+// the files rejected by the filters, see walk.
+func (c *config) walkFiltered(fsys fs.FS, f func(dirpath string, de fs.DirEntry) error) error {
+	if c.filter == nil && c.globs == nil && c.rename == nil && !c.skipEmpty && !c.skipEmptyDirs {
+		return c.walkRoot(fsys, f)
+	}
+	type dir struct {
+		parent  string
+		de      fs.DirEntry
+		emitted bool
+	}
+	var pending []dir // folders from the root down to the current entry.
+	return c.walkRoot(fsys, func(dirpath string, de fs.DirEntry) error {
+		for len(pending) > 0 {
+			last := pending[len(pending)-1]
+			if path.Join(last.parent, last.de.Name()) == dirpath {
+				break
+			}
+			pending = pending[:len(pending)-1]
+		}
+		if de.IsDir() {
+			pending = append(pending, dir{parent: dirpath, de: de})
+			return nil
+		}
+		keep, err := c.keep(path.Join(dirpath, de.Name()), de)
+		if err != nil || !keep {
+			return err
+		}
+		for i := range pending {
+			if pending[i].emitted {
+				continue
+			}
+			if err := f(pending[i].parent, pending[i].de); err != nil {
+				return err
+			}
+			pending[i].emitted = true
+		}
+		return f(dirpath, de)
+	})
+}
+
+// walkRoot is like Walk over the root of fsys but does not enter folders
+// deeper than set by WithMaxDepth nor hidden ones with WithSkipHidden.
+func (c *config) walkRoot(fsys fs.FS, f func(dirpath string, de fs.DirEntry) error) error {
+	if c.maxDepth <= 0 && !c.skipHidden {
+		return Walk(fsys, ".", f)
+	}
+	return WalkDir(fsys, ".", func(name string, de fs.DirEntry, err error) error {
+		if err != nil || name == "." {
+			return err
+		}
+		if c.skipHidden && strings.HasPrefix(de.Name(), ".") {
+			if de.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		err = f(path.Dir(name), de)
+		if err == nil && de.IsDir() && c.maxDepth > 0 && strings.Count(name, "/")+1 >= c.maxDepth {
+			return fs.SkipDir
+		}
+		return err
+	})
+}
+
+// keep reports whether the embedded file at path passes the filters.
+func (c *config) keep(path string, de fs.DirEntry) (bool, error) {
+	if c.filter != nil && !c.filter(path, de) {
+		return false, nil
+	}
+	if c.rename != nil && c.rename(path) == "" {
+		return false, nil
+	}
+	if c.skipEmpty {
+		info, err := de.Info()
+		if err != nil || info.Size() == 0 {
+			return false, err
+		}
+	}
+	if c.globs != nil {
+		return c.globs.Match(path)
+	}
+	return true, nil
+}
+
+// WithDest sets the folder Tree, Touch, Create and Patch extract into
+// instead of the current working directory. Functions taking a dest
+// argument ignore it.
+func WithDest(dir string) Option {
+	return func(c *config) { c.dest = dir }
+}
+
+// destination returns the folder set by WithDest in opts, if any,
+// or the current working directory.
+func destination(opts []Option) string {
+	if dest := newConfig(opts).dest; dest != "" {
+		return dest
+	}
+	return "."
+}
+
+// WithDirPerm sets the permission folders are created with.
+// Defaults to 0755 (before umask).
+func WithDirPerm(perm os.FileMode) Option {
+	return func(c *config) { c.dirPerm = perm }
+}
+
+// WithFileModeFunc sets a callback which is called with the embedded path of
+// every file written. The file is chmod'ed to the returned mode after
+// its contents are written, i.e. to make shell scripts executable.
+func WithFileModeFunc(mode func(path string) os.FileMode) Option {
+	return func(c *config) { c.fileMode = mode }
+}
+
+// WithFileMode is like WithFileModeFunc but sets the same mode for every
+// file. Files are created with mode and chmod'ed to it once written so
+// their mode is exactly mode regardless of the process' umask, i.e. when
+// extracting into a location shared with other users.
+func WithFileMode(mode os.FileMode) Option {
+	return WithFileModeFunc(func(string) os.FileMode { return mode })
+}
+
+// WithFilter makes extraction only write the files for which keep returns
+// true. keep is called with the slash separated path of every file in the
+// embedded filesystem, never with folders nor destination paths. Folders are
+// only created if they hold at least one kept file. If several filters
+// are passed files must pass all of them.
+func WithFilter(keep func(path string, de fs.DirEntry) bool) Option {
+	return func(c *config) {
+		prev := c.filter
+		if prev == nil {
+			c.filter = keep
+			return
+		}
+		c.filter = func(path string, de fs.DirEntry) bool {
+			return prev(path, de) && keep(path, de)
+		}
+	}
+}
+
+// WithGlobs is like WithFilter but selects files with glob patterns.
+// Extraction fails with path.ErrBadPattern if a pattern is malformed.
+// It may be combined with WithFilter in which case files must pass both.
+func WithGlobs(g Globs) Option {
+	return func(c *config) { c.globs = &g }
+}
+
+// WithRename sets a callback mapping the slash separated path of every
+// embedded file to the slash separated path inside dest Create writes it
+// to, i.e. to write "dist/app.v2.js" to "app.js". Files for which it
+// returns an empty string are skipped. Folders are not recreated from the
+// embedded ones but created as needed by the renamed files. The returned
+// path is used as is, suffixes are not trimmed from it as with WithGunzip.
+// Paths resolving outside dest fail with ErrOutsideDest.
+func WithRename(rename func(embedPath string) (destPath string)) Option {
+	return func(c *config) { c.rename = rename }
+}
+
+// WithStripComponents removes the first n slash separated elements from
+// the path of every embedded file before writing it inside dest, as tar's
+// --strip-components does, so an fsys rooted at "internal/assets" may be
+// extracted as if rooted at its contents with n set to 2. Files with n or
+// fewer elements are skipped. Folders are created as with WithRename,
+// which it applies after if both are passed. An n of 0 or less strips
+// nothing and has no effect.
+func WithStripComponents(n int) Option {
+	return func(c *config) {
+		if n <= 0 {
+			return
+		}
+		prev := c.rename
+		c.rename = func(name string) string {
+			if prev != nil {
+				name = prev(name)
+			}
+			parts := strings.SplitN(name, "/", n+1)
+			if len(parts) <= n {
+				return ""
+			}
+			return parts[n]
+		}
+	}
+}
+
+// WithMaxDepth limits extraction to the top depth levels of fsys: the
+// files and folders with at most depth slash separated elements in their
+// path are extracted, folders at the limit are created empty and anything
+// inside them is never read. With WithFilter, WithGlobs, WithSkipEmptyDirs
+// or any other option creating folders only for the files they hold,
+// folders at the limit are not created as none of their files is kept.
+// A depth of 0 means no limit.
+func WithMaxDepth(depth int) Option {
+	return func(c *config) { c.maxDepth = depth }
+}
+
+// WithSkipHidden makes extraction skip the files and folders whose name
+// starts with a dot, such as ".DS_Store" or ".git", along with everything
+// inside hidden folders, which are never read.
+func WithSkipHidden() Option {
+	return func(c *config) { c.skipHidden = true }
+}
+
+// WithSkipEmpty makes extraction skip the embedded files which are empty,
+// such as placeholders picked up by accident, as if rejected by WithFilter.
+func WithSkipEmpty() Option {
+	return func(c *config) { c.skipEmpty = true }
+}
+
+// WithSkipEmptyDirs makes extraction create a folder only right before
+// writing the first file inside it, so folders which hold no files, such
+// as those git would not track, never appear on disk. The parents of a
+// folder with files are created along with it.
+func WithSkipEmptyDirs() Option {
+	return func(c *config) { c.skipEmptyDirs = true }
+}
+
+// WithModTime sets the access and modification time of every written file
+// and created folder to t. embed.FS has no modification times so extracted
+// files otherwise get the time of extraction; a fixed t, such as the
+// build's commit time, along with the deterministic order of Walk makes
+// extractions reproducible, metadata included. Folders are set once their
+// contents are written. Folders created for WithRename are not set.
+func WithModTime(t time.Time) Option {
+	return func(c *config) { c.modTime = t }
+}
+
+// WithHook sets a callback called for every path on disk an extraction
+// acts on, i.e. to log them. action is one of "mkdir", "create",
+// "overwrite" or "skip", the latter for existing files left untouched.
+// de is the embedded entry for path. A folder's "mkdir" is reported
+// before anything inside it. CreateParallel may call hook
+// concurrently.
+func WithHook(hook func(path string, de fs.DirEntry, action string)) Option {
+	return func(c *config) { c.hook = hook }
+}
+
+// Decision is what to do with a file on disk about to be overwritten.
+type Decision int
+
+const (
+	// Overwrite replaces the file's contents.
+	Overwrite Decision = iota
+	// Skip leaves the file untouched.
+	Skip
+	// Backup renames the file to its path with a ".bak" suffix, replacing
+	// any previous backup, and then writes the embedded contents.
+	Backup
+)
+
+// WithOverwritePolicy sets a callback which decides what to do with every
+// file on disk Create is about to overwrite. path is the file's path on
+// disk, embedInfo describes the embedded file and diskInfo the existing one.
+func WithOverwritePolicy(policy func(path string, embedInfo, diskInfo fs.FileInfo) Decision) Option {
+	return func(c *config) { c.overwrite = policy }
+}
+
+// WithSymlinks sets a callback which is called with the embedded path of every
+// file written. If it reports the file is a link Create makes a symbolic
+// link to target with os.Symlink in its place instead of copying its
+// contents, replacing whatever was at its path. embed.FS can't hold links
+// so this allows keeping placeholder files and a mapping of their targets.
+// WithFileModeFunc and WithModTime do not apply to links. On platforms which
+// do not support symbolic links extraction fails with os.Symlink's *os.LinkError.
+func WithSymlinks(links func(path string) (target string, isLink bool)) Option {
+	return func(c *config) { c.symlink = links }
+}
+
+// WithAtomicWrites makes Create write every file to a temporary file
+// first and rename it into place after a successful copy, so an
+// interrupted extraction never leaves a truncated file behind.
+func WithAtomicWrites() Option {
+	return func(c *config) { c.atomic = true }
+}
+
+// WithGunzip makes Create decompress the embedded files ending in ".gz"
+// and write them without the suffix, so assets may be embedded gzipped to
+// keep binaries small. Other files are copied verbatim. A file which is
+// not a valid gzip stream fails the extraction with an error naming it.
+func WithGunzip() Option {
+	return func(c *config) { c.gunzip = true }
+}
+
+// WithGzipOutput makes Create compress the embedded files matching glob,
+// a pattern with the syntax of Globs, with gzip as they are written and
+// append ".gz" to their name on disk, i.e. to store large assets which are
+// served with gzip encoding. It is the inverse of WithGunzip. Extraction
+// fails with path.ErrBadPattern if glob is malformed.
+func WithGzipOutput(glob string) Option {
+	return func(c *config) { c.gzipOutput = glob }
+}
+
+// WithContinueOnError makes extraction carry on past the files and folders
+// it fails to write, i.e. for a best effort cache warm up. The contents of
+// a folder which could not be created are skipped. Once done the errors
+// are returned joined as by errors.Join, each a *PathError naming the
+// failed path, so the result is nil only if every path succeeded.
+// Errors reading fsys and context cancellation still stop the walk.
+func WithContinueOnError() Option {
+	return func(c *config) { c.continueOnError = true }
+}
+
+// WithPruneEmptyDirs makes Prune also remove the folders
+// which are left empty after pruning.
+func WithPruneEmptyDirs() Option {
+	return func(c *config) { c.pruneEmptyDirs = true }
+}
@@ -0,0 +1,831 @@
+package rebed_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/soypat/rebed"
+)
+
+func TestCreateToModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permission bits not supported on windows")
+	}
+	dest := t.TempDir()
+	err := rebed.CreateTo(testFS, dest,
+		rebed.WithDirPerm(0700),
+		rebed.WithFileModeFunc(func(path string) os.FileMode {
+			if filepath.Base(path) == "file" {
+				return 0755
+			}
+			return 0600
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]os.FileMode{
+		"testFS":                     0700 | os.ModeDir,
+		"testFS/folder/subfolder":    0700 | os.ModeDir,
+		"testFS/file":                0755,
+		"testFS/folder/fileInfolder": 0600,
+		"testFS/folder/subfolder/fileinsubfolder": 0600,
+	} {
+		info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode() != want {
+			t.Errorf("%q: got mode %v, want %v", path, info.Mode(), want)
+		}
+	}
+}
+
+func TestFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permission bits not supported on windows")
+	}
+	for _, atomic := range []bool{false, true} {
+		dest := t.TempDir()
+		// 0666 is what files are created with, the umask clears
+		// group and other write bits unless chmod'ed.
+		opts := []rebed.Option{rebed.WithFileMode(0666)}
+		if atomic {
+			opts = append(opts, rebed.WithAtomicWrites())
+		}
+		err := rebed.CreateTo(testFS, dest, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(filepath.Join(dest, "testFS", "folder", "fileInfolder"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode() != 0666 {
+			t.Errorf("atomic=%v: got mode %v, want %v", atomic, info.Mode(), os.FileMode(0666))
+		}
+	}
+}
+
+func TestDest(t *testing.T) {
+	extractors := map[string]func(fsys fs.FS, opts ...rebed.Option) error{
+		"Tree":   rebed.Tree,
+		"Touch":  rebed.Touch,
+		"Create": rebed.Create,
+		"Patch":  rebed.Patch,
+	}
+	for name, extract := range extractors {
+		dest := t.TempDir()
+		err := extract(testFS, rebed.WithDest(dest))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(dest, "testFS", "folder", "subfolder")); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestDetectExecutable(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permission bits not supported on windows")
+	}
+	fsys := fstest.MapFS{
+		"bin/run.sh": {Data: []byte("#!/bin/sh\necho hi\n")},
+		"bin/tool":   {Data: []byte("\x7fELF\x02\x01\x01")},
+		"bin/s":      {Data: []byte("#")},
+		"README.md":  {Data: []byte("# readme")},
+		"empty":      {},
+	}
+	dest := t.TempDir()
+	err := rebed.CreateTo(fsys, dest, rebed.WithDetectExecutable())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, exec := range map[string]bool{
+		"bin/run.sh": true,
+		"bin/tool":   true,
+		"bin/s":      false,
+		"README.md":  false,
+		"empty":      false,
+	} {
+		info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode()&0111 != 0; got != exec {
+			t.Errorf("%q: got mode %v, want executable %v", name, info.Mode(), exec)
+		}
+	}
+}
+
+func TestDirPerm(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permission bits not supported on windows")
+	}
+	extractors := map[string]func(fsys fs.FS, dest string, opts ...rebed.Option) error{
+		"Tree":  rebed.TreeTo,
+		"Touch": rebed.TouchTo,
+		"Patch": rebed.PatchTo,
+	}
+	for name, extract := range extractors {
+		dest := t.TempDir()
+		err := extract(testFS, dest, rebed.WithDirPerm(0700))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, dir := range []string{"testFS", "testFS/folder", "testFS/folder/subfolder"} {
+			info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(dir)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0700 {
+				t.Errorf("%s: %q: got perm %v, want %v", name, dir, info.Mode().Perm(), os.FileMode(0700))
+			}
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	keepSubfolder := rebed.WithFilter(func(path string, de fs.DirEntry) bool {
+		return path == "testFS/folder/subfolder/fileinsubfolder"
+	})
+	keepFile := rebed.WithFilter(func(path string, de fs.DirEntry) bool {
+		return path == "testFS/file"
+	})
+	for _, test := range []struct {
+		extract  func(fsys fs.FS, dest string, opts ...rebed.Option) error
+		filter   rebed.Option
+		exist    []string
+		notExist []string
+	}{
+		{
+			extract:  rebed.CreateTo,
+			filter:   keepSubfolder,
+			exist:    []string{"testFS/folder/subfolder/fileinsubfolder"},
+			notExist: []string{"testFS/file", "testFS/folder/fileInfolder"},
+		},
+		{
+			extract:  rebed.PatchTo,
+			filter:   keepFile,
+			exist:    []string{"testFS/file"},
+			notExist: []string{"testFS/folder"},
+		},
+		{
+			extract:  rebed.TreeTo,
+			filter:   keepFile,
+			exist:    []string{"testFS"},
+			notExist: []string{"testFS/file", "testFS/folder"},
+		},
+	} {
+		dest := t.TempDir()
+		err := test.extract(testFS, dest, test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range test.exist {
+			if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(path))); err != nil {
+				t.Error(err)
+			}
+		}
+		for _, path := range test.notExist {
+			if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(path))); !os.IsNotExist(err) {
+				t.Errorf("expected %q to be filtered out, got %v", path, err)
+			}
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dist/app.v2.js":    {Data: []byte("app")},
+		"dist/css/site.css": {Data: []byte("css")},
+		"dist/app.js.map":   {Data: []byte("map")},
+	}
+	rename := rebed.WithRename(func(embedPath string) string {
+		switch embedPath {
+		case "dist/app.v2.js":
+			return "app.js"
+		case "dist/css/site.css":
+			return "static/site.css"
+		}
+		return ""
+	})
+	dest := t.TempDir()
+	err := rebed.CreateTo(fsys, dest, rename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err = filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+		rel, _ := filepath.Rel(dest, path)
+		got = append(got, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".", "app.js", "static", "static/site.css"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got tree %q, want %q", got, want)
+	}
+
+	err = rebed.CreateTo(fsys, dest, rebed.WithRename(func(string) string { return "../escape" }))
+	if !errors.Is(err, rebed.ErrOutsideDest) {
+		t.Errorf("expected %v, got %v", rebed.ErrOutsideDest, err)
+	}
+}
+
+func TestStripComponents(t *testing.T) {
+	fsys := fstest.MapFS{
+		"top.txt":                        {Data: []byte("top")},
+		"internal/readme.md":             {Data: []byte("readme")},
+		"internal/assets/index.html":     {Data: []byte("<html>")},
+		"internal/assets/css/styles.css": {Data: []byte("body{}")},
+	}
+	for n, want := range map[int][]string{
+		-1: {".", "internal", "internal/assets", "internal/assets/css", "internal/assets/css/styles.css",
+			"internal/assets/index.html", "internal/readme.md", "top.txt"},
+		1: {".", "assets", "assets/css", "assets/css/styles.css", "assets/index.html", "readme.md"},
+		2: {".", "css", "css/styles.css", "index.html"},
+	} {
+		dest := t.TempDir()
+		err := rebed.CreateTo(fsys, dest, rebed.WithStripComponents(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		err = filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+			rel, _ := filepath.Rel(dest, path)
+			got = append(got, filepath.ToSlash(rel))
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("n=%d: got tree %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestStripComponentsExtract(t *testing.T) {
+	fsys := fstest.MapFS{
+		"top.txt":                        {Data: []byte("top")},
+		"internal/readme.md":             {Data: []byte("readme")},
+		"internal/assets/css/styles.css": {Data: []byte("body{}")},
+	}
+	tree := []string{".", "assets", "assets/css"}
+	all := append(tree, "assets/css/styles.css", "readme.md")
+	for name, test := range map[string]struct {
+		extract func(fs.FS, string, ...rebed.Option) error
+		want    []string
+	}{
+		"Tree":       {rebed.TreeTo, tree},
+		"Touch":      {rebed.TouchTo, all},
+		"TouchReset": {rebed.TouchReset, all},
+		"Patch":      {rebed.PatchTo, all},
+		"Sync":       {rebed.Sync, all},
+	} {
+		dest := t.TempDir()
+		err := os.WriteFile(filepath.Join(dest, "stray"), nil, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != "Sync" {
+			test.want = append(test.want[:len(test.want):len(test.want)], "stray")
+		}
+		err = test.extract(fsys, dest, rebed.WithStripComponents(1))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var got []string
+		err = filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+			rel, _ := filepath.Rel(dest, path)
+			got = append(got, filepath.ToSlash(rel))
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got tree %q, want %q", name, got, test.want)
+		}
+	}
+
+	err := rebed.TreeStrict(fsys, t.TempDir(), rebed.WithStripComponents(1))
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("TreeStrict: expected %v, got %v", errors.ErrUnsupported, err)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	for depth, want := range map[int][]string{
+		1: {".", "testFS"},
+		2: {".", "testFS", "testFS/file", "testFS/folder"},
+		3: {".", "testFS", "testFS/file", "testFS/folder", "testFS/folder/fileInfolder", "testFS/folder/subfolder"},
+	} {
+		dest := t.TempDir()
+		err := rebed.CreateTo(testFS, dest, rebed.WithMaxDepth(depth))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		err = filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+			rel, _ := filepath.Rel(dest, path)
+			got = append(got, filepath.ToSlash(rel))
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("depth %d: got tree %q, want %q", depth, got, want)
+		}
+	}
+
+	// folders at the limit hold no kept file.
+	dest := t.TempDir()
+	keepAll := rebed.WithFilter(func(string, fs.DirEntry) bool { return true })
+	err := rebed.CreateTo(testFS, dest, rebed.WithMaxDepth(2), keepAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "testFS", "folder")); !os.IsNotExist(err) {
+		t.Errorf("expected folder at the limit not created with a filter, got %v", err)
+	}
+}
+
+func TestSkipHidden(t *testing.T) {
+	fsys := fstest.MapFS{
+		".DS_Store":               {},
+		".git/config":             {},
+		".git/objects/ab/cdef":    {},
+		"web/.cache/deep/file.js": {},
+		"web/.env":                {},
+		"web/index.html":          {},
+		"web/img/.hidden/a.png":   {},
+		"web/img/logo.png":        {},
+	}
+	dest := t.TempDir()
+	err := rebed.CreateTo(fsys, dest, rebed.WithSkipHidden())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err = filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+		rel, _ := filepath.Rel(dest, path)
+		got = append(got, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".", "web", "web/img", "web/img/logo.png", "web/index.html"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got tree %q, want %q", got, want)
+	}
+}
+
+func TestSkipEmpty(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/.keep":    {},
+		"a/data.txt": {Data: []byte("data")},
+		"b/.gitkeep": {},
+		"empty.txt":  {},
+	}
+	dest := t.TempDir()
+	err := rebed.CreateTo(fsys, dest, rebed.WithSkipEmpty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "a", "data.txt")); err != nil {
+		t.Error(err)
+	}
+	for _, path := range []string{"a/.keep", "b", "empty.txt"} {
+		if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(path))); !os.IsNotExist(err) {
+			t.Errorf("expected empty %q skipped, got %v", path, err)
+		}
+	}
+}
+
+func TestSkipEmptyDirs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/b/c/file": {Data: []byte("data")},
+		"a/empty":    {Mode: fs.ModeDir},
+		"d/e":        {Mode: fs.ModeDir},
+	}
+	dest := t.TempDir()
+	err := rebed.CreateTo(fsys, dest, rebed.WithSkipEmptyDirs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "a", "b", "c", "file")); err != nil {
+		t.Error(err)
+	}
+	for _, path := range []string{"a/empty", "d"} {
+		if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(path))); !os.IsNotExist(err) {
+			t.Errorf("expected empty folder %q skipped, got %v", path, err)
+		}
+	}
+}
+
+func TestModTime(t *testing.T) {
+	stamp := time.Date(2021, 2, 16, 12, 0, 0, 0, time.UTC)
+	for name, create := range map[string]func(dest string, opts ...rebed.Option) error{
+		"CreateTo": func(dest string, opts ...rebed.Option) error { return rebed.CreateTo(testFS, dest, opts...) },
+		"CreateParallel": func(dest string, opts ...rebed.Option) error {
+			return rebed.CreateParallel(testFS, dest, 2, opts...)
+		},
+	} {
+		dest := t.TempDir()
+		err := create(dest, rebed.WithModTime(stamp))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = rebed.Walk(testFS, ".", func(dirpath string, de fs.DirEntry) error {
+			fullpath := path.Join(dirpath, de.Name())
+			info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(fullpath)))
+			if err != nil {
+				return err
+			}
+			if !info.ModTime().Equal(stamp) {
+				t.Errorf("%s: %q: got modtime %v, want %v", name, fullpath, info.ModTime(), stamp)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHook(t *testing.T) {
+	for name, test := range map[string]struct {
+		extract func(fsys fs.FS, dest string, opts ...rebed.Option) error
+		want    map[string]string
+	}{
+		"Tree": {rebed.TreeTo, map[string]string{
+			"testFS": "mkdir", "testFS/folder": "mkdir", "testFS/folder/subfolder": "mkdir",
+		}},
+		"Touch": {rebed.TouchTo, map[string]string{
+			"testFS": "mkdir", "testFS/folder": "mkdir", "testFS/folder/subfolder": "mkdir",
+			"testFS/file": "skip", "testFS/folder/fileInfolder": "create", "testFS/folder/subfolder/fileinsubfolder": "create",
+		}},
+		"Create": {rebed.CreateTo, map[string]string{
+			"testFS": "mkdir", "testFS/folder": "mkdir", "testFS/folder/subfolder": "mkdir",
+			"testFS/file": "overwrite", "testFS/folder/fileInfolder": "create", "testFS/folder/subfolder/fileinsubfolder": "create",
+		}},
+		"Patch": {rebed.PatchTo, map[string]string{
+			"testFS": "mkdir", "testFS/folder": "mkdir", "testFS/folder/subfolder": "mkdir",
+			"testFS/file": "skip", "testFS/folder/fileInfolder": "create", "testFS/folder/subfolder/fileinsubfolder": "create",
+		}},
+	} {
+		dest := t.TempDir()
+		err := os.MkdirAll(filepath.Join(dest, "testFS"), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(dest, "testFS", "file"), nil, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string)
+		err = test.extract(testFS, dest, rebed.WithHook(func(path string, de fs.DirEntry, action string) {
+			rel, err := filepath.Rel(dest, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filepath.Base(path) != de.Name() {
+				t.Errorf("%s: entry %q passed for path %q", name, de.Name(), path)
+			}
+			got[filepath.ToSlash(rel)] = action
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got actions %v, want %v", name, got, test.want)
+		}
+	}
+}
+
+func TestOverwritePolicy(t *testing.T) {
+	dest := t.TempDir()
+	err := rebed.TouchTo(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decisions := map[string]rebed.Decision{
+		"file":            rebed.Skip,
+		"fileInfolder":    rebed.Backup,
+		"fileinsubfolder": rebed.Overwrite,
+	}
+	for name := range decisions {
+		err = filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d.Name() == name {
+				err = os.WriteFile(path, []byte("old "+name), 0644)
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = rebed.CreateTo(testFS, dest, rebed.WithOverwritePolicy(func(path string, embedInfo, diskInfo fs.FileInfo) rebed.Decision {
+		if embedInfo.Name() != diskInfo.Name() || filepath.Base(path) != diskInfo.Name() {
+			t.Errorf("mismatched info for %q: %q, %q", path, embedInfo.Name(), diskInfo.Name())
+		}
+		return decisions[diskInfo.Name()]
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for embedPath, want := range map[string]string{
+		"testFS/file":                                 "old file",
+		"testFS/folder/fileInfolder.bak":              "old fileInfolder",
+		"testFS/folder/fileInfolder":                  "",
+		"testFS/folder/subfolder/fileinsubfolder":     "",
+		"testFS/folder/subfolder/fileinsubfolder.bak": "-",
+	} {
+		got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(embedPath)))
+		if want == "-" {
+			if !os.IsNotExist(err) {
+				t.Errorf("%q: expected no backup, got %v", embedPath, err)
+			}
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if want == "" {
+			b, err := testFS.ReadFile(embedPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want = string(b)
+		}
+		if string(got) != want {
+			t.Errorf("%q: got %q, want %q", embedPath, got, want)
+		}
+	}
+}
+
+func TestGunzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("body { color: red }"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"assets/style.css.gz": {Data: buf.Bytes()},
+		"assets/index.html":   {Data: []byte("<html>")},
+	}
+	dest := t.TempDir()
+	err := rebed.CreateTo(fsys, dest, rebed.WithGunzip())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"assets/style.css":  "body { color: red }",
+		"assets/index.html": "<html>",
+	} {
+		got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%q: got %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dest, "assets", "style.css.gz")); !os.IsNotExist(err) {
+		t.Errorf("expected .gz suffix stripped, got %v", err)
+	}
+
+	for data, want := range map[string]error{
+		"this is not a gzip stream":       gzip.ErrHeader,
+		"short":                           io.ErrUnexpectedEOF,
+		string(buf.Bytes()[:buf.Len()-4]): io.ErrUnexpectedEOF, // truncated trailer.
+	} {
+		fsys["assets/bad.gz"] = &fstest.MapFile{Data: []byte(data)}
+		err = rebed.CreateTo(fsys, t.TempDir(), rebed.WithGunzip())
+		var perr *rebed.PathError
+		if !errors.As(err, &perr) || perr.Path != "assets/bad.gz" || !errors.Is(err, want) {
+			t.Errorf("expected %v for assets/bad.gz, got %v", want, err)
+		}
+	}
+}
+
+func TestLineEnding(t *testing.T) {
+	mixed := "a\nb\r\nc\rd\r\r\n\r"
+	// "\r\n" straddling the 32KiB chunks files are copied in.
+	long := strings.Repeat("x", 32*1024-1) + "\r\n"
+	fsys := fstest.MapFS{
+		"mixed.txt": {Data: []byte(mixed)},
+		"long.txt":  {Data: []byte(long)},
+		"image.bin": {Data: []byte(mixed)},
+	}
+	text := rebed.Globs{Include: []string{"*.txt"}}
+	for ending, want := range map[rebed.LineEnding][2]string{
+		rebed.LF:   {"a\nb\nc\rd\r\n\r", strings.Repeat("x", 32*1024-1) + "\n"},
+		rebed.CRLF: {"a\r\nb\r\nc\rd\r\r\n\r", long},
+	} {
+		dest := t.TempDir()
+		err := rebed.CreateTo(fsys, dest, rebed.WithLineEnding(ending, text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, want := range map[string]string{
+			"mixed.txt": want[0],
+			"long.txt":  want[1],
+			"image.bin": mixed,
+		} {
+			got, err := os.ReadFile(filepath.Join(dest, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Errorf("ending %d: %q: got %q, want %q", ending, name, got, want)
+			}
+		}
+	}
+
+	// progress counts the embedded bytes, not the converted ones.
+	var last rebed.ProgressEvent
+	err := rebed.CreateTo(fstest.MapFS{"a.txt": {Data: []byte("a\nb\nc\n")}}, t.TempDir(),
+		rebed.WithLineEnding(rebed.CRLF, text), rebed.WithProgress(func(e rebed.ProgressEvent) { last = e }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.FileBytes != last.FileSize || last.FileSize != 6 {
+		t.Errorf("got FileBytes %d of FileSize %d, want 6 of 6", last.FileBytes, last.FileSize)
+	}
+}
+
+func TestGzipOutput(t *testing.T) {
+	big := strings.Repeat(`{"key": "value"}`, 4096)
+	fsys := fstest.MapFS{
+		"data/big.json":      {Data: []byte(big)},
+		"data/index.html":    {Data: []byte("<html>")},
+		"data/nested/a.json": {Data: []byte("{}")},
+	}
+	dest := t.TempDir()
+	err := rebed.CreateTo(fsys, dest, rebed.WithGzipOutput("**/*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"data/big.json.gz":      big,
+		"data/nested/a.json.gz": "{}",
+	} {
+		f, err := os.Open(filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(zr) // fails without the gzip trailer.
+		f.Close()
+		if err != nil {
+			t.Fatalf("%q: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%q: decompressed contents differ from embedded ones", name)
+		}
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "data", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "<html>" {
+		t.Errorf("unmatched file changed: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "data", "big.json")); !os.IsNotExist(err) {
+		t.Errorf("expected only the compressed file, got %v", err)
+	}
+
+	err = rebed.CreateTo(fsys, t.TempDir(), rebed.WithGzipOutput("["))
+	if !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("expected %v, got %v", path.ErrBadPattern, err)
+	}
+}
+
+func TestContinueOnError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":     {Data: []byte("a")},
+		"bad.gz":    {Data: []byte("not a gzip stream")},
+		"dir/x":     {Data: []byte("x")},
+		"dir/sub/y": {Data: []byte("y")},
+		"z.txt":     {Data: []byte("z")},
+	}
+	extractors := map[string]func(fsys fs.FS, dest string, opts ...rebed.Option) error{
+		"Create": rebed.CreateTo,
+		"CreateParallel": func(fsys fs.FS, dest string, opts ...rebed.Option) error {
+			return rebed.CreateParallel(fsys, dest, 2, opts...)
+		},
+	}
+	for name, extract := range extractors {
+		dest := t.TempDir()
+		// a file where folder dir goes makes it fail.
+		if err := os.WriteFile(filepath.Join(dest, "dir"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		err := extract(fsys, dest, rebed.WithGunzip())
+		if err == nil {
+			t.Fatalf("%s: expected fail fast error", name)
+		}
+		if _, err := os.Stat(filepath.Join(dest, "z.txt")); name == "Create" && !os.IsNotExist(err) {
+			t.Errorf("%s: expected extraction to stop at first error, got %v", name, err)
+		}
+
+		err = extract(fsys, dest, rebed.WithGunzip(), rebed.WithContinueOnError())
+		joined, ok := err.(interface{ Unwrap() []error })
+		if !ok {
+			t.Fatalf("%s: expected joined errors, got %v", name, err)
+		}
+		var failed []string
+		for _, err := range joined.Unwrap() {
+			var perr *rebed.PathError
+			if !errors.As(err, &perr) {
+				t.Fatalf("%s: expected *rebed.PathError, got %v", name, err)
+			}
+			failed = append(failed, perr.Path)
+		}
+		sort.Strings(failed)
+		if want := []string{"bad.gz", "dir"}; !reflect.DeepEqual(failed, want) {
+			t.Errorf("%s: got failed paths %q, want %q", name, failed, want)
+		}
+		for _, name := range []string{"a.txt", "z.txt"} {
+			if _, err := os.Stat(filepath.Join(dest, name)); err != nil {
+				t.Errorf("expected %s extracted: %v", name, err)
+			}
+		}
+	}
+
+	err := rebed.CreateTo(testFS, t.TempDir(), rebed.WithContinueOnError())
+	if err != nil {
+		t.Errorf("expected nil error when every file succeeds, got %v", err)
+	}
+}
+
+func TestSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+	fsys := fstest.MapFS{
+		"bin/tool-v2": {Data: []byte("#!/bin/sh"), Mode: 0755},
+		"bin/tool":    {}, // placeholder for the link.
+	}
+	links := rebed.WithSymlinks(func(path string) (string, bool) {
+		if path == "bin/tool" {
+			return "tool-v2", true
+		}
+		return "", false
+	})
+	dest := t.TempDir()
+	// an existing file at the link's path is replaced.
+	err := os.MkdirAll(filepath.Join(dest, "bin"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dest, "bin", "tool"), []byte("old"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		// the second run replaces the link itself.
+		err = rebed.CreateTo(fsys, dest, links)
+		if err != nil {
+			t.Fatal(err)
+		}
+		target, err := os.Readlink(filepath.Join(dest, "bin", "tool"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target != "tool-v2" {
+			t.Errorf("got link target %q, want %q", target, "tool-v2")
+		}
+		b, err := os.ReadFile(filepath.Join(dest, "bin", "tool"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "#!/bin/sh" {
+			t.Errorf("got contents through link %q", b)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package rebed
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// Overlay returns an fs.FS which serves the files in folder dir on
+// disk in preference to their counterparts in fsys, so an application
+// may ship its defaults embedded and honor the files its users edited
+// without extracting everything. Files only present in fsys are served
+// from fsys, folders list the entries of both. Errors other than
+// fs.ErrNotExist accessing dir are returned as is.
+func Overlay(fsys fs.FS, dir string) fs.FS {
+	return overlayFS{fsys: fsys, disk: os.DirFS(dir)}
+}
+
+// Open opens the file name for reading from folder dir on disk if it is
+// there and from fsys otherwise. It is the file granular counterpart of
+// Overlay for callers which only need to read single files.
+// Folders are not files and fail with fs.ErrInvalid.
+func Open(fsys fs.FS, dir, name string) (io.ReadCloser, error) {
+	f, err := Overlay(fsys, dir).Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err == nil && info.IsDir() {
+		err = &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+type overlayFS struct {
+	fsys fs.FS
+	disk fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	f, err := o.disk.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.fsys.Open(name)
+	} else if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil || !info.IsDir() {
+		return f, err
+	}
+	entries, err := o.ReadDir(name)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &overlayDir{File: f, entries: entries}, nil
+}
+
+// ReadDir returns the entries of both folders, sorted by name.
+// Entries on disk replace the embedded ones with the same name.
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(o.disk, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fs.ReadDir(o.fsys, name)
+	} else if err != nil {
+		return nil, err
+	}
+	info, err := fs.Stat(o.fsys, name)
+	if err != nil || !info.IsDir() {
+		// the folder on disk hides what is embedded at its path.
+		return entries, nil
+	}
+	embedded, err := fs.ReadDir(o.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	onDisk := make(map[string]bool, len(entries))
+	for _, de := range entries {
+		onDisk[de.Name()] = true
+	}
+	for _, de := range embedded {
+		if !onDisk[de.Name()] {
+			entries = append(entries, de)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// overlayDir is a folder on disk listing the merged entries.
+type overlayDir struct {
+	fs.File
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *overlayDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
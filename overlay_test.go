@@ -0,0 +1,113 @@
+package rebed_test
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func TestOverlay(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.yml":     {Data: []byte("default")},
+		"web/index.html": {Data: []byte("<html>")},
+		"web/style.css":  {Data: []byte("body{}")},
+	}
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "web"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		"config.yml":  "edited",
+		"web/app.js":  "user",
+		"web/logo.sv": "added",
+	} {
+		err = os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	ofs := rebed.Overlay(fsys, dir)
+	err = fstest.TestFS(ofs, "config.yml", "web/index.html", "web/style.css", "web/app.js", "web/logo.sv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"config.yml":     "edited",
+		"web/index.html": "<html>",
+		"web/app.js":     "user",
+	} {
+		got, err := fs.ReadFile(ofs, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%q: got %q, want %q", name, got, want)
+		}
+	}
+	entries, err := fs.ReadDir(ofs, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, de := range entries {
+		names = append(names, de.Name())
+	}
+	if want := []string{"app.js", "index.html", "logo.sv", "style.css"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got entries %q, want %q", names, want)
+	}
+
+	// a missing dir serves fsys as is.
+	ofs = rebed.Overlay(fsys, filepath.Join(dir, "missing"))
+	got, err := fs.ReadFile(ofs, "config.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "default" {
+		t.Errorf("got %q, want embedded contents", got)
+	}
+	if _, err := ofs.Open("../config.yml"); err == nil {
+		t.Error("expected invalid path error")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.yml": {Data: []byte("default")},
+		"theme.css":  {Data: []byte("body{}")},
+		"web/a.html": {Data: []byte("<html>")},
+	}
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte("edited"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"config.yml": "edited",
+		"theme.css":  "body{}",
+	} {
+		rc, err := rebed.Open(fsys, dir, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%q: got %q, want %q", name, got, want)
+		}
+	}
+	for _, name := range []string{"web", "missing.txt", "../config.yml"} {
+		if _, err := rebed.Open(fsys, dir, name); err == nil {
+			t.Errorf("%q: expected error", name)
+		}
+	}
+}
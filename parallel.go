@@ -0,0 +1,113 @@
+package rebed
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"sync"
+)
+
+// CreateParallel is like CreateTo but copies files with workers goroutines.
+// All folders are created before any file is written. The first error
+// encountered stops the remaining copies and is returned, unless
+// WithContinueOnError is set.
+func CreateParallel(fsys fs.FS, dest string, workers int, opts ...Option) error {
+	if workers < 1 {
+		workers = 1
+	}
+	cfg := newConfig(opts)
+	cfg.deferFinish = true // files are written once the walk is done.
+	cp, err := openCheckpoint(cfg.checkpoint)
+	if err != nil {
+		return err
+	}
+	type file struct {
+		fullpath string
+		dst      string
+		de       fs.DirEntry
+	}
+	var files []file
+	err = cfg.walk(fsys, func(dirpath string, de fs.DirEntry) error {
+		fullpath := path.Join(dirpath, de.Name())
+		dst, err := cfg.dst(dest, fullpath, de)
+		if err != nil {
+			return err
+		}
+		if de.IsDir() {
+			return cfg.mkdir(dst, de)
+		}
+		if cp.completed(fullpath) {
+			same, err := cfg.sameContents(fsys, fullpath, dst)
+			if err != nil {
+				return err
+			} else if same {
+				cfg.visit(dst, de, "skip")
+				return nil
+			}
+		}
+		files = append(files, file{fullpath: fullpath, dst: dst, de: de})
+		return nil
+	})
+	if err != nil && !cfg.continueOnError {
+		return cp.finish(cfg.finish(err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		mu       sync.Mutex
+		errs     = []error{err} // folders which failed in continueOnError mode.
+		queue    = make(chan file, workers)
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range queue {
+				if ctx.Err() != nil {
+					continue // drain queue after failure.
+				}
+				err := cfg.writeFile(fsys, f.fullpath, f.dst, f.de)
+				if err == nil {
+					err = cp.record(f.fullpath)
+				}
+				if err == nil {
+					continue
+				}
+				err = &PathError{Path: f.fullpath, Err: err}
+				if cfg.collect(err) {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+					continue
+				}
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+enqueue:
+	for _, f := range files {
+		select {
+		case queue <- f:
+		case <-ctx.Done():
+			break enqueue
+		}
+	}
+	close(queue)
+	wg.Wait()
+	if firstErr != nil {
+		return cp.finish(cfg.finish(firstErr))
+	}
+	err = cfg.finish(errors.Join(errs...))
+	if err == nil {
+		err = cfg.checksums.finish(cfg.wfs, dest)
+	}
+	return cp.finish(err)
+}
@@ -0,0 +1,86 @@
+package rebed_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func TestCreateParallel(t *testing.T) {
+	dest := t.TempDir()
+	err := rebed.CreateParallel(testFS, dest, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFS(t, dest)
+
+	mfs := make(fstest.MapFS)
+	for i := 0; i < 500; i++ {
+		name := fmt.Sprintf("d%d/d%d/file%d", i%7, i%3, i)
+		mfs[name] = &fstest.MapFile{Data: []byte(name)}
+	}
+	var events int
+	dest = t.TempDir()
+	err = rebed.CreateParallel(mfs, dest, 8, rebed.WithProgress(func(rebed.ProgressEvent) { events++ }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := rebed.Diff(mfs, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added)+len(diff.Changed)+len(diff.Removed) != 0 {
+		t.Errorf("extraction does not match: %+v", diff)
+	}
+	if events < 500 {
+		t.Errorf("expected progress for each file, got %d events", events)
+	}
+
+	err = rebed.CreateParallel(readErrFS{mfs}, t.TempDir(), 8)
+	if !errors.Is(err, errRead) {
+		t.Errorf("expected %v, got %v", errRead, err)
+	}
+}
+
+func TestCreateParallelCheckpoint(t *testing.T) {
+	dest := t.TempDir()
+	cpPath := filepath.Join(t.TempDir(), "rebed.checkpoint")
+	failing := failReadFS{FS: testFS, name: "testFS/folder/fileInfolder"}
+	err := rebed.CreateParallel(failing, dest, 1, rebed.WithCheckpoint(cpPath))
+	if !errors.Is(err, errRead) {
+		t.Fatalf("expected %v, got %v", errRead, err)
+	}
+	b, err := os.ReadFile(cpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "\"testFS/file\"\n" {
+		t.Errorf("got checkpoint %q", b)
+	}
+	var skipped []string
+	err = rebed.CreateParallel(testFS, dest, 2, rebed.WithCheckpoint(cpPath), rebed.WithChecksums(rebed.ChecksumSums),
+		rebed.WithHook(func(path string, _ fs.DirEntry, action string) {
+			if action == "skip" {
+				skipped = append(skipped, path)
+			}
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dest, "testFS", "file"); len(skipped) != 1 || skipped[0] != want {
+		t.Errorf("expected only %q skipped, got %q", want, skipped)
+	}
+	if _, err := os.Stat(cpPath); !os.IsNotExist(err) {
+		t.Errorf("expected checkpoint removed on success, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "SHA256SUMS")); err != nil {
+		t.Errorf("expected SHA256SUMS written, got %v", err)
+	}
+	assertMatchesFS(t, dest)
+}
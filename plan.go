@@ -0,0 +1,112 @@
+package rebed
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+)
+
+// Mode selects one of the four extraction behaviors.
+type Mode int
+
+const (
+	// ModeTree only creates the folder structure, see Tree.
+	ModeTree Mode = iota
+	// ModeTouch creates the folder structure with empty files, see Touch.
+	ModeTouch
+	// ModeCreate creates the folder structure and overwrites files, see Create.
+	ModeCreate
+	// ModePatch creates files which are missing, see Patch.
+	ModePatch
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeTree:
+		return "tree"
+	case ModeTouch:
+		return "touch"
+	case ModeCreate:
+		return "create"
+	case ModePatch:
+		return "patch"
+	}
+	return "unknown mode"
+}
+
+// ActionKind is what an extraction does to a single path.
+type ActionKind int
+
+const (
+	// ActionCreate means the path does not exist and will be created.
+	ActionCreate ActionKind = iota
+	// ActionOverwrite means the path exists and its contents will be replaced.
+	ActionOverwrite
+	// ActionSkip means the path exists and will be left untouched.
+	ActionSkip
+)
+
+func (k ActionKind) String() string {
+	switch k {
+	case ActionCreate:
+		return "create"
+	case ActionOverwrite:
+		return "overwrite"
+	case ActionSkip:
+		return "skip"
+	}
+	return "unknown action"
+}
+
+// Action is a planned change to a path on disk.
+type Action struct {
+	// Path on disk the action applies to.
+	Path  string
+	IsDir bool
+	Kind  ActionKind
+}
+
+// Plan reports what extracting fsys inside dest with mode would do
+// without writing anything to disk. Files are not listed for ModeTree
+// since it never touches them. It honors the options as Extract does:
+// filtered files are never listed, renamed ones are at their new path
+// and files WithOverwritePolicy would skip are ActionSkip.
+func Plan(fsys fs.FS, dest string, mode Mode, opts ...Option) ([]Action, error) {
+	cfg := newConfig(opts)
+	var actions []Action
+	err := cfg.read(fsys, func(dirpath string, de fs.DirEntry) error {
+		if mode == ModeTree && !de.IsDir() {
+			return nil
+		}
+		fullpath := path.Join(dirpath, de.Name())
+		dst, err := cfg.dst(dest, fullpath, de)
+		if err != nil {
+			return err
+		}
+		diskInfo, err := cfg.wfs.Stat(dst)
+		missing := errors.Is(err, fs.ErrNotExist)
+		if err != nil && !missing {
+			return err
+		}
+		action := Action{Path: dst, IsDir: de.IsDir(), Kind: ActionSkip}
+		switch {
+		case missing:
+			action.Kind = ActionCreate
+		case mode == ModeCreate && !de.IsDir():
+			action.Kind = ActionOverwrite
+			if cfg.overwrite == nil {
+				break
+			}
+			embedInfo, err := de.Info()
+			if err != nil {
+				return err
+			}
+			if cfg.overwrite(dst, embedInfo, diskInfo) == Skip {
+				action.Kind = ActionSkip
+			}
+		}
+		actions = append(actions, action)
+		return nil
+	})
+	return actions, err
+}
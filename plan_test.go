@@ -0,0 +1,89 @@
+package rebed_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/soypat/rebed"
+)
+
+func TestPlan(t *testing.T) {
+	dest := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dest, "testFS"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dest, "testFS", "file"), []byte("keep"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := map[string]bool{
+		filepath.Join(dest, "testFS"):         true,
+		filepath.Join(dest, "testFS", "file"): true,
+	}
+	for _, mode := range []rebed.Mode{rebed.ModeTree, rebed.ModeTouch, rebed.ModeCreate, rebed.ModePatch} {
+		actions, err := rebed.Plan(testFS, dest, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantN := 6 // 3 folders and 3 files.
+		if mode == rebed.ModeTree {
+			wantN = 3
+		}
+		if len(actions) != wantN {
+			t.Errorf("%v: got %d actions, want %d", mode, len(actions), wantN)
+		}
+		for _, action := range actions {
+			want := rebed.ActionCreate
+			switch {
+			case !existing[action.Path]:
+			case mode == rebed.ModeCreate && !action.IsDir:
+				want = rebed.ActionOverwrite
+			default:
+				want = rebed.ActionSkip
+			}
+			if action.Kind != want {
+				t.Errorf("%v: %q: got %v, want %v", mode, action.Path, action.Kind, want)
+			}
+		}
+	}
+	entries, err := os.ReadDir(filepath.Join(dest, "testFS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Plan wrote to disk, found %d entries", len(entries))
+	}
+
+	// options are honored as extracting with them would.
+	opts := []rebed.Option{
+		rebed.WithFilter(func(path string, _ fs.DirEntry) bool { return path == "testFS/file" }),
+		rebed.WithRename(func(name string) string { return name + ".txt" }),
+		rebed.WithOverwritePolicy(func(string, fs.FileInfo, fs.FileInfo) rebed.Decision { return rebed.Skip }),
+	}
+	actions, err := rebed.Plan(testFS, dest, rebed.ModeCreate, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rebed.Action{
+		{Path: filepath.Join(dest, "testFS"), IsDir: true, Kind: rebed.ActionSkip},
+		{Path: filepath.Join(dest, "testFS", "file.txt"), Kind: rebed.ActionCreate},
+	}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("got actions %v, want %v", actions, want)
+	}
+	err = os.Rename(filepath.Join(dest, "testFS", "file"), filepath.Join(dest, "testFS", "file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions, err = rebed.Plan(testFS, dest, rebed.ModeCreate, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 2 || actions[1].Kind != rebed.ActionSkip {
+		t.Errorf("expected file kept by overwrite policy skipped, got %v", actions)
+	}
+}
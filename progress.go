@@ -0,0 +1,85 @@
+package rebed
+
+import (
+	"io"
+	"sync"
+)
+
+// ProgressEvent reports the progress of an extraction.
+type ProgressEvent struct {
+	// Path is the embedded path of the file being written.
+	Path string
+	// FileBytes is the number of bytes of Path written so far, counted
+	// before WithLineEnding and WithGzipOutput transform them, so it
+	// reaches FileSize once done unless Path is decompressed or a template.
+	FileBytes int64
+	// FileSize is the size of Path.
+	FileSize int64
+	// TotalBytes is the number of bytes written so far across all files.
+	TotalBytes int64
+	// FilesDone is the number of files completely written so far.
+	FilesDone int
+}
+
+// WithProgress sets a callback to drive a progress indicator while
+// files are written. f is called after every chunk of a file is written,
+// so large files report progress as they are copied, and once more when
+// the file is complete.
+func WithProgress(f func(ProgressEvent)) Option {
+	return func(c *config) { c.progress = &progress{f: f} }
+}
+
+// progress keeps the running totals of an extraction.
+// It is safe for concurrent use.
+type progress struct {
+	mu         sync.Mutex
+	f          func(ProgressEvent)
+	totalBytes int64
+	filesDone  int
+}
+
+// writer returns a progressWriter reporting the progress of writing
+// the embedded file at path of the given size. Its writer must be set.
+func (p *progress) writer(path string, size int64) *progressWriter {
+	return &progressWriter{p: p, event: ProgressEvent{Path: path, FileSize: size}}
+}
+
+// progressWriter reports every write to w.
+type progressWriter struct {
+	w     io.Writer
+	p     *progress
+	event ProgressEvent
+}
+
+// done reports the file has been completely written.
+func (pw *progressWriter) done() {
+	pw.p.mu.Lock()
+	defer pw.p.mu.Unlock()
+	pw.p.filesDone++
+	pw.report()
+}
+
+// undo takes back the bytes reported for a failed attempt
+// at writing the file from the running totals.
+func (pw *progressWriter) undo() {
+	pw.p.mu.Lock()
+	defer pw.p.mu.Unlock()
+	pw.p.totalBytes -= pw.event.FileBytes
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	n, err := pw.w.Write(b)
+	pw.p.mu.Lock()
+	defer pw.p.mu.Unlock()
+	pw.event.FileBytes += int64(n)
+	pw.p.totalBytes += int64(n)
+	pw.report()
+	return n, err
+}
+
+// report calls the progress callback. pw.p.mu must be held.
+func (pw *progressWriter) report() {
+	pw.event.TotalBytes = pw.p.totalBytes
+	pw.event.FilesDone = pw.p.filesDone
+	pw.p.f(pw.event)
+}
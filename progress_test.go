@@ -0,0 +1,46 @@
+package rebed_test
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func TestProgress(t *testing.T) {
+	const bigSize = 1 << 20
+	mfs := fstest.MapFS{
+		"big":         &fstest.MapFile{Data: bytes.Repeat([]byte("a"), bigSize)},
+		"small/empty": &fstest.MapFile{},
+		"small/file":  &fstest.MapFile{Data: []byte("small")},
+	}
+	var events []rebed.ProgressEvent
+	err := rebed.CreateTo(mfs, t.TempDir(), rebed.WithProgress(func(e rebed.ProgressEvent) {
+		events = append(events, e)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bigEvents int
+	var last rebed.ProgressEvent
+	for _, e := range events {
+		if e.TotalBytes < last.TotalBytes || e.FilesDone < last.FilesDone {
+			t.Errorf("totals went backwards: %+v after %+v", e, last)
+		}
+		if e.FileBytes > e.FileSize {
+			t.Errorf("wrote more than file size: %+v", e)
+		}
+		if e.Path == "big" {
+			bigEvents++
+		}
+		last = e
+	}
+	if bigEvents < 3 {
+		t.Errorf("expected several events for a large file, got %d", bigEvents)
+	}
+	wantTotal := int64(bigSize + len("small"))
+	if last.TotalBytes != wantTotal || last.FilesDone != 3 {
+		t.Errorf("got final event %+v, want %d bytes and 3 files", last, wantTotal)
+	}
+}
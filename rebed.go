@@ -2,143 +2,546 @@
 // to Go's new embed directive.
 //
 // It can recreate the directory structure
-// from the embed.FS type with or without
+// from the embed.FS type (or any other fs.FS) with or without
 // the files it contains. This is useful to
 // expose the filesystem to the end user so they
 // may see and modify the files.
 package rebed
 
 import (
-	"embed"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 )
 
 // folderPerm MkdirAll is called with this permission to prevent restricted folders
 // from being created.  0755=rwxr-xr-x
 const folderPerm os.FileMode = 0755
 
-// Tree creates the target filesystem folder structure.
-func Tree(fsys embed.FS) error {
-	return Walk(fsys, ".", func(dirpath string, de fs.DirEntry) error {
-		fullpath := filepath.Join(dirpath, de.Name())
-		if de.IsDir() {
-			return os.MkdirAll(fullpath, folderPerm)
+var (
+	errNotDir = errors.New("not a directory")
+	errIsDir  = errors.New("is a directory")
+)
+
+// ErrOutsideDest is returned when an entry of fsys would be written
+// outside the destination folder, i.e. one named "../../etc/passwd"
+// in an fs.FS implementation which does not validate its names.
+var ErrOutsideDest = errors.New("path resolves outside destination")
+
+// Tree creates the target filesystem folder structure in the binary's
+// current working directory or the folder set with WithDest.
+func Tree(fsys fs.FS, opts ...Option) error {
+	return TreeTo(fsys, destination(opts), opts...)
+}
+
+// TreeTo creates the target filesystem folder structure
+// inside dest.
+func TreeTo(fsys fs.FS, dest string, opts ...Option) error {
+	return TreeContext(context.Background(), fsys, dest, opts...)
+}
+
+// TreeContext is like TreeTo but stops early with ctx's error
+// once ctx is done.
+func TreeContext(ctx context.Context, fsys fs.FS, dest string, opts ...Option) error {
+	cfg := newConfig(opts)
+	return cfg.walk(fsys, func(dirpath string, de fs.DirEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !de.IsDir() && cfg.rename == nil {
+			return nil
+		}
+		dst, err := cfg.dst(dest, path.Join(dirpath, de.Name()), de)
+		if err != nil {
+			return err
+		}
+		if !de.IsDir() {
+			return cfg.mkdirParent(dst) // the folder of a renamed file.
+		}
+		return cfg.mkdir(dst, de)
+	})
+}
+
+// TreeStrict is like TreeTo but meant for fresh installs: it fails without
+// creating anything if any of the folders it would create already exists
+// in dest. The error lists the existing paths and wraps fs.ErrExist.
+// dest itself may exist. It fails with errors.ErrUnsupported if
+// WithRename or WithStripComponents is passed.
+func TreeStrict(fsys fs.FS, dest string, opts ...Option) error {
+	cfg := newConfig(opts)
+	if cfg.rename != nil {
+		return fmt.Errorf("rebed: TreeStrict does not support renaming: %w", errors.ErrUnsupported)
+	}
+	type dir struct {
+		dst string
+		de  fs.DirEntry
+	}
+	var dirs []dir
+	var existing []string
+	err := cfg.walk(fsys, func(dirpath string, de fs.DirEntry) error {
+		if !de.IsDir() {
+			return nil
+		}
+		dst, err := cfg.dst(dest, path.Join(dirpath, de.Name()), de)
+		if err != nil {
+			return err
+		}
+		_, err = cfg.wfs.Stat(dst)
+		if err == nil {
+			existing = append(existing, dst)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
 		}
+		dirs = append(dirs, dir{dst: dst, de: de})
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	if len(existing) != 0 {
+		return fmt.Errorf("rebed: paths already exist %q: %w", existing, fs.ErrExist)
+	}
+	for _, d := range dirs {
+		if err := cfg.mkdir(d.dst, d.de); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Touch creates the target filesystem folder structure in the binary's
-// current working directory with empty files. Does not modify
-// already existing files.
-func Touch(fsys embed.FS) error {
-	return Walk(fsys, ".", func(dirpath string, de fs.DirEntry) error {
-		fullpath := filepath.Join(dirpath, de.Name())
+// current working directory, or the folder set with WithDest, with empty
+// files. Does not modify already existing files.
+func Touch(fsys fs.FS, opts ...Option) error {
+	return TouchTo(fsys, destination(opts), opts...)
+}
+
+// TouchTo creates the target filesystem folder structure inside
+// dest with empty files. Does not modify already existing files.
+func TouchTo(fsys fs.FS, dest string, opts ...Option) error {
+	return TouchContext(context.Background(), fsys, dest, opts...)
+}
+
+// TouchContext is like TouchTo but stops early with ctx's error
+// once ctx is done.
+func TouchContext(ctx context.Context, fsys fs.FS, dest string, opts ...Option) error {
+	return touch(ctx, fsys, dest, newConfig(opts), nil)
+}
+
+// TouchReport is like TouchTo but also returns the paths of the
+// files and folders it created. Paths which already existed are not listed.
+func TouchReport(fsys fs.FS, dest string, opts ...Option) ([]string, error) {
+	var created []string
+	err := touch(context.Background(), fsys, dest, newConfig(opts), &created)
+	return created, err
+}
+
+// TouchReset is like TouchTo but also truncates the existing files of
+// fsys in dest to empty, i.e. to reset a scaffold of log files while
+// keeping its structure. Touch and Patch leave existing files as they are
+// and Create writes the embedded contents; TouchReset never writes them.
+// Paths in dest which are not in fsys are left alone.
+func TouchReset(fsys fs.FS, dest string, opts ...Option) error {
+	cfg := newConfig(opts)
+	return cfg.walk(fsys, func(dirpath string, de fs.DirEntry) error {
+		fullpath, err := cfg.dst(dest, path.Join(dirpath, de.Name()), de)
+		if err != nil {
+			return err
+		}
 		if de.IsDir() {
-			return os.MkdirAll(fullpath, folderPerm)
+			return cfg.mkdir(fullpath, de)
 		}
-		// unsure how IsNotExist works. this could be improved
-		_, err := os.Stat(fullpath)
-		if os.IsNotExist(err) {
-			_, err = os.Create(fullpath)
+		if err := cfg.mkdirParent(fullpath); err != nil {
+			return err
+		}
+		action := "create"
+		if _, err := cfg.wfs.Stat(fullpath); err == nil {
+			action = "overwrite"
+		}
+		err = createEmpty(cfg.wfs, fullpath)
+		if err == nil {
+			cfg.visit(fullpath, de, action)
 		}
 		return err
 	})
 }
 
 // Create overwrites files of same path/name
-// in binaries current working directory or
-// creates new ones if not exist.
-func Create(fsys embed.FS) error {
-	return Walk(fsys, ".", func(dirpath string, de fs.DirEntry) error {
-		fullpath := filepath.Join(dirpath, de.Name())
+// in binaries current working directory, or the folder
+// set with WithDest, or creates new ones if not exist.
+func Create(fsys fs.FS, opts ...Option) error {
+	return CreateTo(fsys, destination(opts), opts...)
+}
+
+// CreateTo overwrites files of same path/name
+// inside dest or creates new ones if not exist.
+func CreateTo(fsys fs.FS, dest string, opts ...Option) error {
+	return CreateContext(context.Background(), fsys, dest, opts...)
+}
+
+// CreateContext is like CreateTo but stops early with ctx's error
+// once ctx is done. ctx is checked before every file copy.
+func CreateContext(ctx context.Context, fsys fs.FS, dest string, opts ...Option) error {
+	cfg := newConfig(opts)
+	cp, err := openCheckpoint(cfg.checkpoint)
+	if err != nil {
+		return err
+	}
+	err = cfg.walk(fsys, func(dirpath string, de fs.DirEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fullpath := path.Join(dirpath, de.Name())
+		dst, err := cfg.dst(dest, fullpath, de)
+		if err != nil {
+			return err
+		}
 		if de.IsDir() {
-			return os.MkdirAll(fullpath, folderPerm)
+			return cfg.mkdir(dst, de)
+		}
+		if cp.completed(fullpath) {
+			same, err := cfg.sameContents(fsys, fullpath, dst)
+			if err != nil {
+				return err
+			} else if same {
+				cfg.visit(dst, de, "skip")
+				return nil
+			}
+		}
+		err = cfg.writeFile(fsys, fullpath, dst, de)
+		if err == nil {
+			err = cp.record(fullpath)
 		}
-		return embedCopyToFile(fsys, fullpath)
+		return err
 	})
+	if err == nil {
+		err = cfg.checksums.finish(cfg.wfs, dest)
+	}
+	return cp.finish(err)
 }
 
-// Patch creates files which are missing in
-// FS filesystem. Does not modify existing files
-func Patch(fsys embed.FS) error {
-	return Walk(fsys, ".", func(dirpath string, de fs.DirEntry) error {
-		fullpath := filepath.Join(dirpath, de.Name())
+// CreateMissing copies the embedded files which are missing in dest
+// and leaves existing files untouched, keeping any user modifications.
+// Unlike Patch the files it creates get their embedded contents,
+// unlike Create it never overwrites.
+func CreateMissing(fsys fs.FS, dest string, opts ...Option) error {
+	cfg := newConfig(opts)
+	if err := cfg.sumsUnsupported("CreateMissing"); err != nil {
+		return err
+	}
+	return cfg.walk(fsys, func(dirpath string, de fs.DirEntry) error {
+		fullpath := path.Join(dirpath, de.Name())
+		dst, err := cfg.dst(dest, fullpath, de)
+		if err != nil {
+			return err
+		}
 		if de.IsDir() {
-			return os.MkdirAll(fullpath, folderPerm)
+			return cfg.mkdir(dst, de)
+		}
+		_, err = cfg.wfs.Stat(dst)
+		if err == nil {
+			cfg.visit(dst, de, "skip")
+			return nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return cfg.writeFile(fsys, fullpath, dst, de)
+	})
+}
+
+// Patch creates files which are missing in
+// FS filesystem as empty files. Does not modify existing files.
+// See CreateMissing to create missing files with their contents.
+func Patch(fsys fs.FS, opts ...Option) error {
+	return PatchTo(fsys, destination(opts), opts...)
+}
+
+// PatchTo creates files which are missing in
+// dest. Does not modify existing files
+func PatchTo(fsys fs.FS, dest string, opts ...Option) error {
+	return PatchContext(context.Background(), fsys, dest, opts...)
+}
+
+// PatchContext is like PatchTo but stops early with ctx's error
+// once ctx is done.
+func PatchContext(ctx context.Context, fsys fs.FS, dest string, opts ...Option) error {
+	return touch(ctx, fsys, dest, newConfig(opts), nil)
+}
+
+// PatchReport is like PatchTo but also returns the paths of the
+// files and folders it created. Paths which already existed are not listed.
+func PatchReport(fsys fs.FS, dest string, opts ...Option) ([]string, error) {
+	var created []string
+	err := touch(context.Background(), fsys, dest, newConfig(opts), &created)
+	return created, err
+}
+
+// touch creates the folder structure of fsys inside dest with empty files
+// where files are missing. If created is not nil the paths of the newly
+// created files and folders are appended to it.
+func touch(ctx context.Context, fsys fs.FS, dest string, cfg *config, created *[]string) error {
+	return cfg.walk(fsys, func(dirpath string, de fs.DirEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fullpath, err := cfg.dst(dest, path.Join(dirpath, de.Name()), de)
+		if err != nil {
+			return err
+		}
+		isNew := true
+		if de.IsDir() && cfg.rename != nil {
+			return nil // created along with the renamed files.
+		} else if de.IsDir() {
+			_, err = cfg.wfs.Stat(fullpath)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			isNew = err != nil
+			// fails if the existing path is not a folder.
+			err = cfg.mkdir(fullpath, de)
+		} else {
+			isNew, err = cfg.touchFile(fullpath, de)
 		}
-		_, err := os.Stat(fullpath)
-		if os.IsNotExist(err) {
-			_, err = os.Create(fullpath)
+		if err == nil && isNew && created != nil {
+			*created = append(*created, fullpath)
 		}
 		return err
 	})
 }
 
+// touchFile creates an empty file at dst for the embedded file de
+// unless dst exists, and reports whether it did.
+func (c *config) touchFile(dst string, de fs.DirEntry) (created bool, err error) {
+	if err := c.mkdirParent(dst); err != nil {
+		return false, err
+	}
+	_, err = c.wfs.Stat(dst)
+	if err == nil {
+		c.visit(dst, de, "skip") // existing files are left untouched.
+		return false, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	err = createEmpty(c.wfs, dst)
+	if err != nil {
+		return false, err
+	}
+	c.visit(dst, de, "create")
+	return true, nil
+}
+
+// createEmpty creates an empty file at path, truncating it if it exists.
+func createEmpty(wfs WriteFS, path string) error {
+	f, err := wfs.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// copyFunc copies the contents of the embedded file src to dst.
+type copyFunc func(dst io.Writer, src fs.File) error
+
+// bufPool holds the buffers used to copy files so extracting many
+// files does not allocate a new buffer for each one.
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 32*1024)
+		return &b
+	},
+}
+
+// copyBuffer is like io.Copy but uses a buffer from bufPool.
+func copyBuffer(dst io.Writer, src io.Reader) error {
+	bufp := bufPool.Get().(*[]byte)
+	defer bufPool.Put(bufp)
+	// hide dst's ReadFrom method, *os.File's allocates its own buffer
+	// when it can't use a system call.
+	_, err := io.CopyBuffer(struct{ io.Writer }{dst}, src, *bufp)
+	return err
+}
+
 // embedCopyToFile copies an embedded file's contents
-// to a file machine in same relative path
-func embedCopyToFile(fsys embed.FS, path string) error {
+// to a file on disk at dst with copyFn. New files are created
+// with perm before umask.
+func embedCopyToFile(wfs WriteFS, fsys fs.FS, path, dst string, perm os.FileMode, copyFn copyFunc) (err error) {
 	fi, err := fsys.Open(path)
 	if err != nil {
 		return err
 	}
-	fo, err := os.Create(path)
+	defer fi.Close()
+	fo, err := wfs.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
 	if err != nil {
 		return err
 	}
-	_, err = io.Copy(fo, fi)
-	return err
+	defer func() {
+		// a failed close may mean the contents were not written.
+		if cerr := fo.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return copyFn(fo, fi)
+}
+
+// joinDest joins the slash separated path name relative to the root of
+// an fs.FS to the folder dest on disk, failing with ErrOutsideDest if the
+// result is not inside dest.
+func joinDest(dest, name string) (string, error) {
+	dst := filepath.Join(dest, filepath.FromSlash(name))
+	rel, err := filepath.Rel(dest, dst)
+	if err != nil {
+		return "", err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrOutsideDest
+	}
+	return dst, nil
 }
 
 // Walk expects a path to a directory.
 // f called on every file/directory found recursively.
 // It is not guaranteed to stay in main package import path.
 //
-// f's first argument is the relative/absolute path to directory being scanned.
-func Walk(fsys embed.FS, startPath string, f func(path string, de fs.DirEntry) error) error {
-	folders := make([]string, 0) // buffer of folders to process
-	WalkDir(fsys, startPath, func(dirpath string, de fs.DirEntry) error {
-		if de.IsDir() {
-			folders = append(folders, filepath.Join(dirpath, de.Name()))
+// startPath is a slash separated path cleaned as by path.Clean, "." being
+// the root; an absolute one fails with fs.ErrInvalid and one which is not a
+// folder of fsys with an error naming it. f is passed the path of the folder
+// holding each entry, "." at the root. The first error from f or reading a
+// folder aborts the walk. Entries are visited as by WalkDir, each folder
+// before its contents.
+func Walk(fsys fs.FS, startPath string, f func(path string, de fs.DirEntry) error) error {
+	cleaned, err := cleanStartPath(startPath)
+	if err != nil {
+		return err
+	}
+	info, err := fs.Stat(fsys, cleaned)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// a mistyped startPath should not look like an empty folder.
+		return fmt.Errorf("rebed: startPath %q not found in fs.FS: %w", startPath, err)
+	case err != nil:
+		return err
+	case !info.IsDir():
+		return &fs.PathError{Op: "walk", Path: startPath, Err: errNotDir}
+	}
+	return WalkDir(fsys, cleaned, func(fullpath string, de fs.DirEntry, err error) error {
+		if err != nil || fullpath == cleaned {
+			return err // startPath itself is not passed to f.
 		}
-		return f(dirpath, de)
+		return f(path.Dir(fullpath), de)
 	})
-	n := len(folders)
-	for n != 0 {
-		for i := 0; i < n; i++ {
-			WalkDir(fsys, folders[i], func(dirpath string, de fs.DirEntry) error {
-				if de.IsDir() {
-					folders = append(folders, filepath.Join(dirpath, de.Name()))
-				}
-				return f(dirpath, de)
-			})
-		}
-		// we process n folders at a time, add new folders while
-		//processing n folders, then discard those n folders once finished
-		// and resume with a new n list of folders
-		var newFolders int = len(folders) - n
-		folders = folders[n : n+newFolders] // if found 0 new folders, end
-		n = len(folders)
+}
+
+// sortedFS sorts the entries returned by ReadDir by name, which fs.ReadDirFS
+// implementations should but are not forced to do.
+type sortedFS struct {
+	fs.FS
+}
+
+func (s sortedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(s.FS, name)
+	sortEntries(entries)
+	return entries, err
+}
+
+// sortEntries sorts entries by name.
+func sortEntries(entries []fs.DirEntry) {
+	less := func(i, j int) bool { return entries[i].Name() < entries[j].Name() }
+	if !sort.SliceIsSorted(entries, less) {
+		sort.Slice(entries, less)
 	}
-	return nil
 }
 
-// WalkDir applies f to every file/folder in embedded directory fsys.
-// It is not guaranteed to stay in main package import path.
-//
-// f's first argument is the relative/absolute path to directory being scanned.
-func WalkDir(fsys embed.FS, startPath string, f func(path string, de fs.DirEntry) error) error {
-	items, err := fsys.ReadDir(startPath)
+// cleanStartPath cleans a walk's start path and checks it is valid in an fs.FS.
+func cleanStartPath(startPath string) (string, error) {
+	cleaned := path.Clean(startPath)
+	if !fs.ValidPath(cleaned) {
+		return "", &fs.PathError{Op: "walk", Path: startPath, Err: fs.ErrInvalid}
+	}
+	return cleaned, nil
+}
+
+// WalkDepth is like Walk but also passes f the depth of every entry below
+// startPath, i.e. to indent a printed tree: 1 for the entries of startPath,
+// 2 for those of its folders and so on.
+func WalkDepth(fsys fs.FS, startPath string, f func(path string, de fs.DirEntry, depth int) error) error {
+	cleaned := path.Clean(startPath)
+	return Walk(fsys, startPath, func(dirpath string, de fs.DirEntry) error {
+		depth := 1
+		if dirpath != cleaned {
+			rel := dirpath
+			if cleaned != "." {
+				rel = dirpath[len(cleaned)+1:]
+			}
+			depth += strings.Count(rel, "/") + 1
+		}
+		return f(dirpath, de, depth)
+	})
+}
+
+// WalkDir is like fs.WalkDir: it calls fn for startPath and every file and
+// folder found recursively inside it with their full slash separated path,
+// and fn may return fs.SkipDir or fs.SkipAll. Unlike fs.WalkDir startPath is
+// cleaned as in Walk, so an invalid one is passed to fn with fs.ErrInvalid,
+// and entries are visited in lexical order even if fsys's ReadDir method
+// does not sort them.
+func WalkDir(fsys fs.FS, startPath string, fn fs.WalkDirFunc) error {
+	cleaned, err := cleanStartPath(startPath)
 	if err != nil {
+		err = fn(startPath, nil, err)
+		if err == fs.SkipDir || err == fs.SkipAll {
+			return nil
+		}
 		return err
 	}
-	for _, item := range items {
-		if err := f(startPath, item); err != nil {
+	return fs.WalkDir(sortedFS{fsys}, cleaned, fn)
+}
+
+// WalkFiles calls f with the full slash separated path and the contents
+// of every file found recursively in startPath, which is interpreted as
+// in Walk. Folders are skipped. Each file is read whole into memory
+// before calling f so it is best suited to small files; use WalkReaders
+// to stream large files instead.
+func WalkFiles(fsys fs.FS, startPath string, f func(path string, content []byte) error) error {
+	return Walk(fsys, startPath, func(dirpath string, de fs.DirEntry) error {
+		if de.IsDir() {
+			return nil
+		}
+		fullpath := path.Join(dirpath, de.Name())
+		content, err := fs.ReadFile(fsys, fullpath)
+		if err != nil {
 			return err
 		}
-	}
-	return nil
+		return f(fullpath, content)
+	})
+}
+
+// WalkReaders is like WalkFiles but passes f a reader streaming the
+// contents of each file instead of loading them into memory, i.e. to hash
+// large files. The file is closed once f returns, so r must not be used
+// afterwards. An error closing the file is returned if f succeeded.
+func WalkReaders(fsys fs.FS, startPath string, f func(path string, r io.Reader) error) error {
+	return Walk(fsys, startPath, func(dirpath string, de fs.DirEntry) (err error) {
+		if de.IsDir() {
+			return nil
+		}
+		fullpath := path.Join(dirpath, de.Name())
+		fi, err := fsys.Open(fullpath)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := fi.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		return f(fullpath, fi)
+	})
 }
@@ -1,11 +1,21 @@
 package rebed_test
 
 import (
+	"context"
 	"embed"
+	"errors"
+	"fmt"
+	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/soypat/rebed"
 )
@@ -68,7 +78,7 @@ func TestPatch(t *testing.T) {
 	testFileCreation(rebed.Patch, t)
 }
 
-func testFileCreation(rebedder func(embed.FS) error, t *testing.T) {
+func testFileCreation(rebedder func(fs.FS, ...rebed.Option) error, t *testing.T) {
 	// shadow testDir
 	tDir := filepath.Join(testDir, t.Name())
 	setup(tDir, t)
@@ -93,6 +103,451 @@ func testFileCreation(rebedder func(embed.FS) error, t *testing.T) {
 	}
 }
 
+func TestTreeTo(t *testing.T) {
+	dest := t.TempDir()
+	err := rebed.TreeTo(testFS, dest)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rebed.Walk(testFS, ".", func(path string, de fs.DirEntry) error {
+		pathToCreated := filepath.Join(dest, path, de.Name())
+		info, err := os.Stat(pathToCreated)
+		if de.IsDir() {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				t.Errorf("expected a folder %q, got file", pathToCreated)
+			}
+		} else if !os.IsNotExist(err) {
+			t.Errorf("expected no file at %q", pathToCreated)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTouchTo(t *testing.T) {
+	testFileCreationTo(rebed.TouchTo, t)
+}
+
+func TestCreateTo(t *testing.T) {
+	dest := testFileCreationTo(rebed.CreateTo, t)
+	err := rebed.Walk(testFS, ".", func(path string, de fs.DirEntry) error {
+		if de.IsDir() {
+			return nil
+		}
+		embedPath := filepath.Join(path, de.Name())
+		want, err := testFS.ReadFile(embedPath)
+		if err != nil {
+			return err
+		}
+		got, err := os.ReadFile(filepath.Join(dest, embedPath))
+		if err != nil {
+			return err
+		}
+		if string(got) != string(want) {
+			t.Errorf("%q: content mismatch, got %q, want %q", embedPath, got, want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPatchTo(t *testing.T) {
+	testFileCreationTo(rebed.PatchTo, t)
+}
+
+// testFileCreationTo runs rebedder on a fresh temporary
+// directory and returns it after checking every entry exists.
+func testFileCreationTo(rebedder func(fs.FS, string, ...rebed.Option) error, t *testing.T) string {
+	dest := t.TempDir()
+	err := rebedder(testFS, dest)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rebed.Walk(testFS, ".", func(path string, de fs.DirEntry) error {
+		pathToCreated := filepath.Join(dest, path, de.Name())
+		info, err := os.Stat(pathToCreated)
+		if err != nil {
+			return err
+		}
+		if de.IsDir() != info.IsDir() {
+			t.Errorf("expected folder/file got file/folder %q", pathToCreated)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	return dest
+}
+
+func TestWalkError(t *testing.T) {
+	errDeep := errors.New("deep error")
+	deepPath := filepath.Join("testFS", "folder", "subfolder", "fileinsubfolder")
+	var visitedAfter int
+	var failed bool
+	err := rebed.Walk(testFS, ".", func(path string, de fs.DirEntry) error {
+		if failed {
+			visitedAfter++
+		}
+		if filepath.Join(path, de.Name()) == deepPath {
+			failed = true
+			return errDeep
+		}
+		return nil
+	})
+	if !errors.Is(err, errDeep) {
+		t.Errorf("expected %v, got %v", errDeep, err)
+	}
+	if visitedAfter != 0 {
+		t.Errorf("walk continued for %d entries after error", visitedAfter)
+	}
+}
+
+func TestCreateContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dest := t.TempDir()
+	err := rebed.CreateContext(ctx, testFS, dest)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no entries created after cancel, got %d", len(entries))
+	}
+
+	// cancel midway through extraction.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	fsys := cancelFS{FS: testFS, cancel: cancel, name: "testFS/file"}
+	err = rebed.CreateContext(ctx, fsys, dest)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	_, err = os.Stat(filepath.Join(dest, "testFS", "folder", "fileInfolder"))
+	if !os.IsNotExist(err) {
+		t.Errorf("expected extraction to stop after cancel, got %v", err)
+	}
+}
+
+func TestPatchReport(t *testing.T) {
+	dest := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dest, "testFS"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dest, "testFS", "file"), []byte("keep"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := rebed.PatchReport(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dest, "testFS", "folder"),
+		filepath.Join(dest, "testFS", "folder", "fileInfolder"),
+		filepath.Join(dest, "testFS", "folder", "subfolder"),
+		filepath.Join(dest, "testFS", "folder", "subfolder", "fileinsubfolder"),
+	}
+	sort.Strings(created)
+	if strings.Join(created, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got created %q, want %q", created, want)
+	}
+	b, err := os.ReadFile(filepath.Join(dest, "testFS", "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "keep" {
+		t.Errorf("existing file was modified: %q", b)
+	}
+	// second run should create nothing.
+	created, err = rebed.TouchReport(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 0 {
+		t.Errorf("expected nothing created on second run, got %q", created)
+	}
+}
+
+func TestTouchExisting(t *testing.T) {
+	dest := t.TempDir()
+	existing := filepath.Join(dest, "testFS", "file")
+	err := os.MkdirAll(filepath.Dir(existing), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(existing, []byte("keep"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.TouchTo(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "keep" {
+		t.Errorf("existing file was modified: %q", b)
+	}
+	missing := filepath.Join(dest, "testFS", "folder", "fileInfolder")
+	info, err := os.Stat(missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("expected missing file to be created empty, got size %d", info.Size())
+	}
+}
+
+func TestTouchReset(t *testing.T) {
+	dest := t.TempDir()
+	err := rebed.CreateTo(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra := filepath.Join(dest, "testFS", "extra.log")
+	err = os.WriteFile(extra, []byte("keep"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Remove(filepath.Join(dest, "testFS", "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.TouchReset(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.Walk(testFS, ".", func(dirpath string, de fs.DirEntry) error {
+		info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(path.Join(dirpath, de.Name()))))
+		if err != nil {
+			return err
+		}
+		if !de.IsDir() && info.Size() != 0 {
+			t.Errorf("%q: expected empty file, got size %d", de.Name(), info.Size())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "keep" {
+		t.Errorf("file not in fsys was modified: %q", b)
+	}
+}
+
+func TestTouchUnreadableParent(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced")
+	}
+	dest := t.TempDir()
+	parent := filepath.Join(dest, "testFS")
+	err := os.Mkdir(parent, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(parent, 0755) // allow TempDir cleanup.
+	err = rebed.TouchTo(testFS, dest)
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected %v, got %v", fs.ErrPermission, err)
+	}
+}
+
+// cancelFS calls cancel when name is opened.
+type cancelFS struct {
+	fs.FS
+	cancel func()
+	name   string
+}
+
+func (c cancelFS) Open(name string) (fs.File, error) {
+	if name == c.name {
+		c.cancel()
+	}
+	return c.FS.Open(name)
+}
+
+func TestWalkDeep(t *testing.T) {
+	mfs := make(fstest.MapFS)
+	want := make(map[string]bool)
+	// several branches of differing depth, each deeper than the last.
+	for branch := 0; branch < 5; branch++ {
+		dir := fmt.Sprintf("b%d", branch)
+		for depth := 0; depth < 4*(branch+1); depth++ {
+			want[dir] = true
+			file := path.Join(dir, fmt.Sprintf("f%d", depth))
+			mfs[file] = &fstest.MapFile{Data: []byte(file)}
+			want[file] = true
+			dir = path.Join(dir, fmt.Sprintf("d%d", depth))
+		}
+	}
+	visited := make(map[string]bool)
+	err := rebed.Walk(mfs, ".", func(dirpath string, de fs.DirEntry) error {
+		fullpath := path.Join(dirpath, de.Name())
+		if dirpath != "." && !visited[dirpath] {
+			t.Errorf("%q visited before its parent", fullpath)
+		}
+		if visited[fullpath] {
+			t.Errorf("%q visited twice", fullpath)
+		}
+		visited[fullpath] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("visited %d paths, want %d", len(visited), len(want))
+		for p := range want {
+			if !visited[p] {
+				t.Errorf("%q not visited", p)
+			}
+		}
+	}
+	err = rebed.Walk(mfs, "b0/f0", func(string, fs.DirEntry) error { return nil })
+	if err == nil {
+		t.Error("expected error walking a file")
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	var got []string
+	err := rebed.WalkDir(testFS, "./testFS/folder/", func(fullpath string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		got = append(got, fullpath)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"testFS/folder", "testFS/folder/fileInfolder", "testFS/folder/subfolder", "testFS/folder/subfolder/fileinsubfolder"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got paths %q, want %q", got, want)
+	}
+	got = got[:0]
+	err = rebed.WalkDir(testFS, ".", func(fullpath string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		got = append(got, fullpath)
+		if fullpath == "testFS/folder" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{".", "testFS", "testFS/file", "testFS/folder"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got paths %q, want %q", got, want)
+	}
+	var gotErr error
+	err = rebed.WalkDir(testFS, "/testFS", func(fullpath string, de fs.DirEntry, err error) error {
+		gotErr = err
+		return err
+	})
+	if !errors.Is(err, fs.ErrInvalid) || !errors.Is(gotErr, fs.ErrInvalid) {
+		t.Errorf("expected %v passed to fn and returned, got %v", fs.ErrInvalid, err)
+	}
+}
+
+func TestWalkStartPath(t *testing.T) {
+	all := []string{"testFS", "testFS/file", "testFS/folder", "testFS/folder/fileInfolder",
+		"testFS/folder/subfolder", "testFS/folder/subfolder/fileinsubfolder"}
+	sub := []string{"testFS/folder/fileInfolder", "testFS/folder/subfolder", "testFS/folder/subfolder/fileinsubfolder"}
+	for startPath, want := range map[string][]string{
+		".":                all,
+		"./":               all,
+		"testFS/folder":    sub,
+		"./testFS/folder/": sub,
+		"testFS//folder":   sub,
+	} {
+		var got []string
+		err := rebed.Walk(testFS, startPath, func(dirpath string, de fs.DirEntry) error {
+			fullpath := path.Join(dirpath, de.Name())
+			if fullpath != dirpath+"/"+de.Name() && dirpath != "." {
+				t.Errorf("%q: dirpath %q is not clean", startPath, dirpath)
+			}
+			got = append(got, fullpath)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got paths %q, want %q", startPath, got, want)
+		}
+	}
+	for _, startPath := range []string{"/testFS", "/", "../testFS"} {
+		err := rebed.Walk(testFS, startPath, func(string, fs.DirEntry) error { return nil })
+		if !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("%q: expected %v, got %v", startPath, fs.ErrInvalid, err)
+		}
+	}
+	called := false
+	err := rebed.Walk(testFS, "testFS/fodler", func(string, fs.DirEntry) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), `"testFS/fodler" not found`) || called {
+		t.Errorf("expected error naming missing start path, got %v", err)
+	}
+	err = rebed.Walk(testFS, "testFS/file", func(string, fs.DirEntry) error { return nil })
+	if err == nil {
+		t.Error("expected error walking a file")
+	}
+}
+
+// openOnlyFS hides any method of the embedded FS other than Open.
+type openOnlyFS struct{ fs.FS }
+
+func TestCreateToSub(t *testing.T) {
+	sub, err := fs.Sub(testFS, "testFS/folder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fsys := range []fs.FS{sub, openOnlyFS{sub}} {
+		dest := t.TempDir()
+		err = rebed.CreateTo(fsys, dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"fileInfolder", "subfolder/fileinsubfolder"} {
+			want, err := testFS.ReadFile("testFS/folder/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(filepath.Join(dest, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("%q: content mismatch, got %q, want %q", name, got, want)
+			}
+		}
+	}
+}
+
 func cleanup(path string) error {
 	matcher := filepath.Join(path, "*")
 	filesToRemove, err := filepath.Glob(matcher)
@@ -104,3 +559,445 @@ func cleanup(path string) error {
 	}
 	return nil
 }
+
+func TestCreateNoLeak(t *testing.T) {
+	const nfiles = 2000
+	mfs := make(fstest.MapFS)
+	for i := 0; i < nfiles; i++ {
+		mfs[fmt.Sprintf("dir%d/file%d", i%10, i)] = &fstest.MapFile{Data: []byte("data")}
+	}
+	fsys := &closeCountFS{FS: mfs}
+	fdsBefore := openFDs()
+	err := rebed.CreateTo(fsys, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fsys.open != 0 {
+		t.Errorf("%d embedded files left open", fsys.open)
+	}
+	// allow for some slack from the runtime and testing package.
+	if fdsBefore >= 0 && openFDs()-fdsBefore > 10 {
+		t.Errorf("file descriptors leaked: %d before, %d after", fdsBefore, openFDs())
+	}
+}
+
+// openFDs returns the number of open file descriptors of the
+// process or -1 if they can't be counted on this platform.
+func openFDs() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
+
+// closeCountFS keeps count of the files which are open.
+type closeCountFS struct {
+	fs.FS
+	open int
+}
+
+func (c *closeCountFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(c.FS, name)
+}
+
+func (c *closeCountFS) Open(name string) (fs.File, error) {
+	f, err := c.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	c.open++
+	return &closeCountFile{File: f, fsys: c}, nil
+}
+
+type closeCountFile struct {
+	fs.File
+	fsys *closeCountFS
+}
+
+func (c *closeCountFile) Close() error {
+	c.fsys.open--
+	return c.File.Close()
+}
+
+func TestTouchNoLeak(t *testing.T) {
+	const nfiles = 3000
+	mfs := make(fstest.MapFS)
+	for i := 0; i < nfiles; i++ {
+		mfs[fmt.Sprintf("dir%d/file%d", i%10, i)] = &fstest.MapFile{}
+	}
+	for _, extract := range []func(fs.FS, string, ...rebed.Option) error{rebed.TouchTo, rebed.PatchTo} {
+		fdsBefore := openFDs()
+		err := extract(mfs, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fdsBefore >= 0 && openFDs()-fdsBefore > 10 {
+			t.Errorf("file descriptors leaked: %d before, %d after", fdsBefore, openFDs())
+		}
+	}
+}
+
+func TestCreateMissing(t *testing.T) {
+	dest := t.TempDir()
+	existing := filepath.Join(dest, "testFS", "file")
+	err := os.MkdirAll(filepath.Dir(existing), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(existing, []byte("user modified"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.CreateMissing(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := rebed.Diff(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 0 || !reflect.DeepEqual(diff.Changed, []string{"testFS/file"}) {
+		t.Errorf("expected only the existing file to differ, got %+v", diff)
+	}
+}
+
+func TestTraversal(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ok":                 {Data: []byte("ok")},
+		"../../etc/passwd":   {Data: []byte("root")},
+		"../outside/evil.gz": {Data: []byte("evil")},
+	}
+	extractors := map[string]func(fsys fs.FS, dest string, opts ...rebed.Option) error{
+		"Tree":          rebed.TreeTo,
+		"Touch":         rebed.TouchTo,
+		"Create":        rebed.CreateTo,
+		"CreateMissing": rebed.CreateMissing,
+		"CreateParallel": func(fsys fs.FS, dest string, opts ...rebed.Option) error {
+			return rebed.CreateParallel(fsys, dest, 2, opts...)
+		},
+		"CreateIfChanged": func(fsys fs.FS, dest string, opts ...rebed.Option) error {
+			_, err := rebed.CreateIfChanged(fsys, dest, opts...)
+			return err
+		},
+		"Clean": func(fsys fs.FS, dest string, opts ...rebed.Option) error {
+			return rebed.Clean(fsys, dest)
+		},
+	}
+	for name, extract := range extractors {
+		root := t.TempDir()
+		dest := filepath.Join(root, "a", "b")
+		err := os.MkdirAll(dest, 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = extract(fsys, dest)
+		var perr *rebed.PathError
+		if !errors.Is(err, rebed.ErrOutsideDest) || !errors.As(err, &perr) || perr.Path != ".." {
+			t.Errorf("%s: expected ErrOutsideDest for \"..\", got %v", name, err)
+		}
+		entries, err := os.ReadDir(filepath.Join(root, "a"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Errorf("%s: expected nothing written outside dest, got %d entries", name, len(entries))
+		}
+	}
+
+	// stripping the suffix of "...gz" leaves "..".
+	err := rebed.ExtractFile(fstest.MapFS{"...gz": {}}, "...gz", t.TempDir(), rebed.WithGunzip())
+	if !errors.Is(err, rebed.ErrOutsideDest) {
+		t.Errorf("expected ErrOutsideDest for stripped name, got %v", err)
+	}
+}
+
+func TestWalkFiles(t *testing.T) {
+	got := make(map[string]string)
+	err := rebed.WalkFiles(testFS, "testFS/folder", func(path string, content []byte) error {
+		got[path] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make(map[string]string)
+	for _, name := range []string{"testFS/folder/fileInfolder", "testFS/folder/subfolder/fileinsubfolder"} {
+		b, err := testFS.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[name] = string(b)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	errStop := errors.New("stop")
+	err = rebed.WalkFiles(testFS, ".", func(path string, content []byte) error {
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("expected callback error returned, got %v", err)
+	}
+}
+
+func TestWalkReaders(t *testing.T) {
+	fsys := &closeCountFS{FS: testFS}
+	got := make(map[string]string)
+	err := rebed.WalkReaders(fsys, "testFS/folder", func(path string, r io.Reader) error {
+		b, err := io.ReadAll(r)
+		got[path] = string(b)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make(map[string]string)
+	for _, name := range []string{"testFS/folder/fileInfolder", "testFS/folder/subfolder/fileinsubfolder"} {
+		b, err := testFS.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[name] = string(b)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	errStop := errors.New("stop")
+	err = rebed.WalkReaders(fsys, ".", func(path string, r io.Reader) error {
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("expected callback error returned, got %v", err)
+	}
+	if fsys.open != 0 {
+		t.Errorf("%d embedded files left open", fsys.open)
+	}
+}
+
+func TestWalkOrder(t *testing.T) {
+	fsys := reverseFS{fstest.MapFS{
+		"b/2":   {},
+		"b/1":   {},
+		"a":     {},
+		"c/z/y": {},
+		"c/x":   {},
+	}}
+	want := []string{"a", "b", "b/1", "b/2", "c", "c/x", "c/z", "c/z/y"}
+	for i := 0; i < 2; i++ {
+		var got []string
+		err := rebed.Walk(fsys, ".", func(dirpath string, de fs.DirEntry) error {
+			got = append(got, path.Join(dirpath, de.Name()))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got order %q, want %q", got, want)
+		}
+	}
+	var got []string
+	err := rebed.WalkDir(fsys, "b", func(fullpath string, de fs.DirEntry, err error) error {
+		got = append(got, fullpath)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"b", "b/1", "b/2"}) {
+		t.Errorf("WalkDir: got order %q", got)
+	}
+}
+
+// reverseFS returns directory entries in reverse lexical order.
+type reverseFS struct {
+	fstest.MapFS
+}
+
+func (r reverseFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := r.MapFS.ReadDir(name)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() > entries[j].Name() })
+	return entries, err
+}
+
+func TestTreeStrict(t *testing.T) {
+	dest := t.TempDir()
+	err := rebed.TreeStrict(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"testFS", "testFS/folder", "testFS/folder/subfolder"} {
+		info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(dir)))
+		if err != nil || !info.IsDir() {
+			t.Errorf("expected folder %q created: %v", dir, err)
+		}
+	}
+
+	dest = t.TempDir()
+	existing := filepath.Join(dest, "testFS")
+	err = os.Mkdir(existing, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.TreeStrict(testFS, dest)
+	if !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("expected fs.ErrExist, got %v", err)
+	}
+	if want := fmt.Sprintf("%q", []string{existing}); !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error to list only %s, got %v", want, err)
+	}
+	entries, err := os.ReadDir(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected nothing created on conflict, got %d entries", len(entries))
+	}
+}
+
+func TestSlashPaths(t *testing.T) {
+	// the paths passed to an fs.FS must be slash separated on every
+	// platform, the nested tree catches backslashes on windows.
+	fsys := strictFS{fstest.MapFS{
+		"a/b/c/file.txt": {Data: []byte("file")},
+		"a/b/other.txt":  {Data: []byte("other")},
+		"a/top.txt":      {Data: []byte("top")},
+	}}
+	dest := t.TempDir()
+	// steps after CreateTo compare against the tree it extracted.
+	for _, step := range []struct {
+		name string
+		run  func() error
+	}{
+		{"CreateTo", func() error { return rebed.CreateTo(fsys, dest) }},
+		{"CreateMissing", func() error { return rebed.CreateMissing(fsys, t.TempDir()) }},
+		{"CreateParallel", func() error { return rebed.CreateParallel(fsys, t.TempDir(), 2) }},
+		{"ExtractFile", func() error { return rebed.ExtractFile(fsys, "a/b/c/file.txt", t.TempDir()) }},
+		{"CreateIfChanged", func() error {
+			written, err := rebed.CreateIfChanged(fsys, dest)
+			if err == nil && len(written) != 0 {
+				t.Errorf("expected no files rewritten, got %q", written)
+			}
+			return err
+		}},
+		{"Sync", func() error { return rebed.Sync(fsys, dest) }},
+		{"Verify", func() error { return rebed.Verify(fsys, dest) }},
+		{"Diff", func() error {
+			diff, err := rebed.Diff(fsys, dest)
+			if err == nil && len(diff.Added)+len(diff.Removed)+len(diff.Changed) != 0 {
+				t.Errorf("expected no differences, got %+v", diff)
+			}
+			return err
+		}},
+	} {
+		if err := step.run(); err != nil {
+			t.Errorf("%s: %v", step.name, err)
+		}
+	}
+}
+
+// strictFS fails for paths which are not valid in an fs.FS or
+// contain backslashes, which fs.ValidPath allows.
+type strictFS struct {
+	fsys fs.FS
+}
+
+func (s strictFS) check(op, name string) error {
+	if !fs.ValidPath(name) || strings.Contains(name, `\`) {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return nil
+}
+
+func (s strictFS) Open(name string) (fs.File, error) {
+	if err := s.check("open", name); err != nil {
+		return nil, err
+	}
+	return s.fsys.Open(name)
+}
+
+func (s strictFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := s.check("readdir", name); err != nil {
+		return nil, err
+	}
+	return fs.ReadDir(s.fsys, name)
+}
+
+func (s strictFS) Stat(name string) (fs.FileInfo, error) {
+	if err := s.check("stat", name); err != nil {
+		return nil, err
+	}
+	return fs.Stat(s.fsys, name)
+}
+
+func TestWalkDepth(t *testing.T) {
+	for startPath, want := range map[string]map[string]int{
+		".": {
+			"testFS":                     1,
+			"testFS/file":                2,
+			"testFS/folder":              2,
+			"testFS/folder/fileInfolder": 3,
+			"testFS/folder/subfolder":    3,
+			"testFS/folder/subfolder/fileinsubfolder": 4,
+		},
+		"./testFS/": {
+			"testFS/file":                             1,
+			"testFS/folder":                           1,
+			"testFS/folder/fileInfolder":              2,
+			"testFS/folder/subfolder":                 2,
+			"testFS/folder/subfolder/fileinsubfolder": 3,
+		},
+	} {
+		got := make(map[string]int)
+		err := rebed.WalkDepth(testFS, startPath, func(dirpath string, de fs.DirEntry, depth int) error {
+			got[path.Join(dirpath, de.Name())] = depth
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got depths %v, want %v", startPath, got, want)
+		}
+	}
+}
+
+func TestWalkParentFirst(t *testing.T) {
+	fsys := reverseFS{fstest.MapFS{
+		"a/b/c/d/file": {},
+		"a/b/c/other":  {},
+		"a/b-c/file":   {},
+		"a/b.txt":      {},
+		"z/y/x":        {},
+		"empty":        {Mode: fs.ModeDir},
+	}}
+	seen := map[string]bool{".": true}
+	err := rebed.Walk(fsys, ".", func(dirpath string, de fs.DirEntry) error {
+		if !seen[dirpath] {
+			t.Errorf("%q passed before its directory %q", de.Name(), dirpath)
+		}
+		seen[path.Join(dirpath, de.Name())] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// lazily created folders under a filter still precede their files.
+	dest := t.TempDir()
+	made := map[string]bool{filepath.Clean(dest): true}
+	err = rebed.CreateTo(fsys, dest,
+		rebed.WithFilter(func(path string, _ fs.DirEntry) bool { return strings.HasSuffix(path, "file") }),
+		rebed.WithHook(func(path string, _ fs.DirEntry, action string) {
+			if !made[filepath.Dir(path)] {
+				t.Errorf("%s %q before its folder", action, path)
+			}
+			made[path] = true
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,109 @@
+// Package rebedtest implements support for testing fs.FS
+// implementations meant to be used with rebed.
+package rebedtest
+
+import (
+	"io/fs"
+	"path"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+// VerifyWalkable checks fsys behaves as rebed expects when walking and
+// extracting it, reporting every violation found to t. It checks rebed.Walk
+// and rebed.WalkDir visit every path fs.WalkDir finds exactly once, folders
+// before their contents and with entries in lexical order, that every entry
+// agrees with fs.Stat on being a folder, and runs fstest.TestFS with the
+// files found. Use it from the tests of a custom fs.FS:
+//
+//	func TestWalkable(t *testing.T) {
+//		rebedtest.VerifyWalkable(t, myFS)
+//	}
+func VerifyWalkable(t testing.TB, fsys fs.FS) {
+	t.Helper()
+	var want []string
+	var files []string
+	err := fs.WalkDir(fsys, ".", func(name string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." {
+			want = append(want, name)
+		}
+		if !de.IsDir() {
+			files = append(files, name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("fs.WalkDir: %v", err)
+		return
+	}
+
+	visited := make(map[string]int, len(want))
+	seenDirs := map[string]bool{".": true}
+	lastName := make(map[string]string)
+	err = rebed.Walk(fsys, ".", func(dirpath string, de fs.DirEntry) error {
+		name := path.Join(dirpath, de.Name())
+		visited[name]++
+		switch {
+		case path.Clean(dirpath) != dirpath:
+			t.Errorf("Walk: %q: directory path is not clean", name)
+		case !seenDirs[dirpath]:
+			t.Errorf("Walk: %q visited before its directory", name)
+		case lastName[dirpath] != "" && lastName[dirpath] >= de.Name():
+			t.Errorf("Walk: %q visited after %q", name, path.Join(dirpath, lastName[dirpath]))
+		}
+		lastName[dirpath] = de.Name()
+		if de.IsDir() {
+			seenDirs[name] = true
+		}
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			t.Errorf("Walk: %q: %v", name, err)
+		} else if info.IsDir() != de.IsDir() {
+			t.Errorf("Walk: %q: entry IsDir %v, fs.Stat IsDir %v", name, de.IsDir(), info.IsDir())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Walk: %v", err)
+	}
+	checkVisited(t, "Walk", want, visited)
+
+	visited = make(map[string]int, len(want))
+	err = rebed.WalkDir(fsys, ".", func(name string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." {
+			visited[name]++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("WalkDir: %v", err)
+	}
+	checkVisited(t, "WalkDir", want, visited)
+
+	if err := fstest.TestFS(fsys, files...); err != nil {
+		t.Errorf("fstest.TestFS: %v", err)
+	}
+}
+
+// checkVisited reports the paths in want not visited exactly
+// once and the visited paths which are not in want.
+func checkVisited(t testing.TB, walk string, want []string, visited map[string]int) {
+	t.Helper()
+	for _, name := range want {
+		if n := visited[name]; n != 1 {
+			t.Errorf("%s: %q visited %d times, want once", walk, name, n)
+		}
+		delete(visited, name)
+	}
+	for name := range visited {
+		t.Errorf("%s: %q visited but not found by fs.WalkDir", walk, name)
+	}
+}
@@ -0,0 +1,52 @@
+package rebedtest_test
+
+import (
+	"fmt"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed/rebedtest"
+)
+
+func TestVerifyWalkable(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":       {Data: []byte("a")},
+		"dir/b.txt":   {Data: []byte("b")},
+		"dir/sub/c":   {Data: []byte("c")},
+		"empty":       {Mode: fs.ModeDir},
+		"z/last.json": {Data: []byte("{}")},
+	}
+	rebedtest.VerifyWalkable(t, fsys)
+
+	rec := &recorder{TB: t}
+	rebedtest.VerifyWalkable(rec, lyingFS{fsys})
+	if len(rec.errors) == 0 {
+		t.Error("expected violations reported for an fs.FS whose entries lie")
+	}
+}
+
+// recorder records the errors reported instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// lyingFS reports every directory entry as a folder.
+type lyingFS struct{ fstest.MapFS }
+
+func (l lyingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := l.MapFS.ReadDir(name)
+	for i, de := range entries {
+		entries[i] = dirEntry{de}
+	}
+	return entries, err
+}
+
+type dirEntry struct{ fs.DirEntry }
+
+func (dirEntry) IsDir() bool { return true }
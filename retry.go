@@ -0,0 +1,31 @@
+package rebed
+
+import "time"
+
+// WithRetry makes extraction retry writing a file up to retries more
+// times when it fails with an error for which transient returns true,
+// i.e. EAGAIN or EBUSY on a network mounted dest. It waits backoff
+// before the first retry and twice as long before each of the following
+// ones. Once out of retries the last error is returned. A nil transient
+// never retries. The bytes written by failed attempts are not counted by
+// CreateStats nor reported by WithProgress.
+func WithRetry(retries int, backoff time.Duration, transient func(error) bool) Option {
+	return func(c *config) {
+		c.retries = retries
+		c.backoff = backoff
+		c.transient = transient
+	}
+}
+
+// retry calls write until it succeeds, fails with an error which is
+// not transient or the retries set by WithRetry are exhausted.
+func (c *config) retry(write func() error) error {
+	err := write()
+	wait := c.backoff
+	for i := 0; i < c.retries && err != nil && c.transient != nil && c.transient(err); i++ {
+		time.Sleep(wait)
+		wait *= 2
+		err = write()
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+package rebed_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/soypat/rebed"
+)
+
+var errBusy = errors.New("device busy")
+
+// flakyFS is a MemFS failing the first fails file opens with errBusy.
+type flakyFS struct {
+	*rebed.MemFS
+	fails int
+	opens int
+}
+
+func (f *flakyFS) OpenFile(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+	f.opens++
+	if f.opens <= f.fails {
+		return nil, errBusy
+	}
+	return f.MemFS.OpenFile(name, flag, perm)
+}
+
+func TestRetry(t *testing.T) {
+	isBusy := func(err error) bool { return errors.Is(err, errBusy) }
+	wfs := &flakyFS{MemFS: &rebed.MemFS{}, fails: 2}
+	err := rebed.CreateTo(testFS, "out", rebed.WithWriteFS(wfs), rebed.WithRetry(2, time.Millisecond, isBusy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := wfs.MapFS()["out/testFS/file"]; got == nil || len(got.Data) != 5 {
+		t.Errorf("expected file written after retries, got %+v", got)
+	}
+
+	wfs = &flakyFS{MemFS: &rebed.MemFS{}, fails: 3}
+	err = rebed.CreateTo(testFS, "out", rebed.WithWriteFS(wfs), rebed.WithRetry(2, time.Millisecond, isBusy))
+	var perr *rebed.PathError
+	if !errors.Is(err, errBusy) || !errors.As(err, &perr) {
+		t.Errorf("expected %v wrapped in *PathError once out of retries, got %v", errBusy, err)
+	}
+
+	wfs = &flakyFS{MemFS: &rebed.MemFS{}, fails: 1}
+	err = rebed.CreateTo(testFS, "out", rebed.WithWriteFS(wfs), rebed.WithRetry(2, time.Millisecond, func(error) bool { return false }))
+	if !errors.Is(err, errBusy) || wfs.opens != 1 {
+		t.Errorf("expected no retry of error which is not transient, got %v after %d opens", err, wfs.opens)
+	}
+}
+
+// failingWriter writes to w but fails every write with errBusy.
+type failingWriter struct {
+	io.WriteCloser
+}
+
+func (w failingWriter) Write(b []byte) (int, error) {
+	n, _ := w.WriteCloser.Write(b)
+	return n, errBusy
+}
+
+// brokenFS is a MemFS whose first fails files fail once written to.
+type brokenFS struct {
+	flakyFS
+}
+
+func (f *brokenFS) OpenFile(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+	f.opens++
+	w, err := f.MemFS.OpenFile(name, flag, perm)
+	if err == nil && f.opens <= f.fails {
+		w = failingWriter{w}
+	}
+	return w, err
+}
+
+func TestRetryCounts(t *testing.T) {
+	_, _, total, err := rebed.Stat(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	isBusy := func(err error) bool { return errors.Is(err, errBusy) }
+	var last rebed.ProgressEvent
+	wfs := &brokenFS{flakyFS{MemFS: &rebed.MemFS{}, fails: 2}}
+	stats, err := rebed.CreateStats(testFS, "out", rebed.WithWriteFS(wfs), rebed.WithRetry(2, time.Millisecond, isBusy),
+		rebed.WithProgress(func(e rebed.ProgressEvent) { last = e }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.BytesWritten != total || last.TotalBytes != total {
+		t.Errorf("expected %d bytes written, got %d counted and %d reported", total, stats.BytesWritten, last.TotalBytes)
+	}
+
+	// a nil transient never retries.
+	wfs = &brokenFS{flakyFS{MemFS: &rebed.MemFS{}, fails: 1}}
+	err = rebed.CreateTo(testFS, "out", rebed.WithWriteFS(wfs), rebed.WithRetry(2, time.Millisecond, nil))
+	if !errors.Is(err, errBusy) || wfs.opens != 1 {
+		t.Errorf("expected no retry without transient, got %v after %d opens", err, wfs.opens)
+	}
+}
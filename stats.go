@@ -0,0 +1,39 @@
+package rebed
+
+import (
+	"context"
+	"io"
+	"io/fs"
+)
+
+// Stats summarizes what an extraction wrote.
+type Stats struct {
+	FilesWritten int
+	// DirsCreated counts the folders created, those which already
+	// existed are not. Folders created for WithRename are not counted.
+	DirsCreated int
+	// BytesWritten is the number of bytes written to files, which
+	// differs from their embedded size with WithGunzip or templates.
+	BytesWritten int64
+}
+
+// CreateStats is like CreateTo but also returns what it wrote, i.e. to
+// log a summary. Files and folders skipped are not counted. On error the
+// returned Stats count what was written before it.
+func CreateStats(fsys fs.FS, dest string, opts ...Option) (Stats, error) {
+	var stats Stats
+	err := CreateContext(context.Background(), fsys, dest, append(opts[:len(opts):len(opts)], func(c *config) { c.stats = &stats })...)
+	return stats, err
+}
+
+// countWriter counts the bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (cw countWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	*cw.n += int64(n)
+	return n, err
+}
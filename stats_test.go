@@ -0,0 +1,44 @@
+package rebed_test
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/soypat/rebed"
+)
+
+func TestCreateStats(t *testing.T) {
+	dest := t.TempDir()
+	stats, err := rebed.CreateStats(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, size, err := rebed.Stat(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := rebed.Stats{FilesWritten: 3, DirsCreated: 3, BytesWritten: size}
+	if stats != want {
+		t.Errorf("got %+v, want %+v", stats, want)
+	}
+	assertMatchesFS(t, dest)
+
+	// existing folders are not created again.
+	stats, err = rebed.CreateStats(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (rebed.Stats{FilesWritten: 3, BytesWritten: size}); stats != want {
+		t.Errorf("existing: got %+v, want %+v", stats, want)
+	}
+
+	stats, err = rebed.CreateStats(testFS, t.TempDir(), rebed.WithSkipEmptyDirs(), rebed.WithFilter(func(path string, _ fs.DirEntry) bool {
+		return path == "testFS/file"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (rebed.Stats{FilesWritten: 1, DirsCreated: 1, BytesWritten: 5}); stats != want {
+		t.Errorf("filtered: got %+v, want %+v", stats, want)
+	}
+}
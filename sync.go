@@ -0,0 +1,224 @@
+package rebed
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Sync makes dest a mirror of fsys. It overwrites every file in dest
+// with its fsys counterpart like CreateTo and then removes every file
+// and folder inside dest which is not present in fsys. dest itself is
+// never removed. It acts on the OS filesystem and fails with
+// errors.ErrUnsupported if WithWriteFS is passed.
+func Sync(fsys fs.FS, dest string, opts ...Option) error {
+	cfg := newConfig(opts)
+	if err := cfg.osOnly("Sync"); err != nil {
+		return err
+	}
+	err := CreateTo(fsys, dest, opts...)
+	if err != nil {
+		return err
+	}
+	_, err = cfg.removeStray(fsys, dest)
+	return err
+}
+
+// Prune removes the files and folders inside dest which are not present
+// in fsys and returns their paths. Files which are present in fsys are
+// left untouched. Folders left empty after pruning are also removed
+// when WithPruneEmptyDirs is passed. Like Sync it fails with
+// errors.ErrUnsupported if WithWriteFS is passed.
+func Prune(fsys fs.FS, dest string, opts ...Option) ([]string, error) {
+	cfg := newConfig(opts)
+	if err := cfg.osOnly("Prune"); err != nil {
+		return nil, err
+	}
+	removed, err := cfg.removeStray(fsys, dest)
+	if err != nil || !cfg.pruneEmptyDirs {
+		return removed, err
+	}
+	return removeEmptyParents(dest, removed)
+}
+
+// osOnly fails unless files are written to the OS filesystem, for the
+// function fn which removes files from dest with the os package.
+func (c *config) osOnly(fn string) error {
+	if _, ok := c.wfs.(osFS); !ok {
+		return fmt.Errorf("rebed: %s does not support WithWriteFS: %w", fn, errors.ErrUnsupported)
+	}
+	return nil
+}
+
+// removeStray removes the files and folders inside dest with no
+// counterpart in fsys and returns their paths. Files are looked up at
+// the name extraction writes them to, see fileName. Stray folders are
+// removed along with their contents.
+func (c *config) removeStray(fsys fs.FS, dest string) ([]string, error) {
+	names, err := c.destNames(fsys)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	err = filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dest {
+			return nil
+		}
+		rel, err := filepath.Rel(dest, path)
+		if err != nil {
+			return err
+		}
+		if names[filepath.ToSlash(rel)] {
+			return nil
+		}
+		err = os.RemoveAll(path)
+		if err != nil {
+			return err
+		}
+		removed = append(removed, path)
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// destNames returns the set of slash separated paths inside dest
+// the files and folders of fsys are extracted to, checksum files
+// included. With a rename callback set the folders are those holding
+// the renamed files.
+func (c *config) destNames(fsys fs.FS) (map[string]bool, error) {
+	names := make(map[string]bool)
+	if c.checksums != nil && c.checksums.mode == ChecksumSums {
+		names[sumsFile] = true
+	}
+	err := Walk(fsys, ".", func(dirpath string, de fs.DirEntry) error {
+		fullpath := path.Join(dirpath, de.Name())
+		switch {
+		case de.IsDir() && c.rename == nil:
+			names[fullpath] = true
+		case !de.IsDir():
+			name := c.fileName(fullpath)
+			if name == "" {
+				return nil // skipped by rename.
+			}
+			name = path.Clean(name)
+			if _, isLink := c.link(fullpath); !isLink && c.checksums != nil && c.checksums.mode == ChecksumSidecar {
+				names[name+sidecarSuffix] = true
+			}
+			for ; name != "." && !names[name]; name = path.Dir(name) {
+				names[name] = true
+			}
+		}
+		return nil
+	})
+	return names, err
+}
+
+// removeEmptyParents removes the parent folders of the removed paths which
+// are left empty, up to but not including dest. The removed folders are
+// appended to removed.
+func removeEmptyParents(dest string, removed []string) ([]string, error) {
+	dest = filepath.Clean(dest)
+	for i := 0; i < len(removed); i++ {
+		dir := filepath.Dir(removed[i])
+		for dir != dest && dir != "." && dir != filepath.Dir(dir) {
+			entries, err := os.ReadDir(dir)
+			if os.IsNotExist(err) {
+				break
+			} else if err != nil {
+				return removed, err
+			}
+			if len(entries) != 0 {
+				break
+			}
+			err = os.Remove(dir)
+			if err != nil {
+				return removed, err
+			}
+			removed = append(removed, dir)
+			dir = filepath.Dir(dir)
+		}
+	}
+	return removed, nil
+}
+
+// PruneEmptyDirs removes the empty folders inside dest, including those
+// only holding empty folders, and returns their paths, children before
+// their parents. dest itself is never removed. It acts on any folder on
+// disk, not only extracted ones.
+func PruneEmptyDirs(dest string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && path != dest {
+			dirs = append(dirs, path)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	// WalkDir visits parents before children so remove in reverse.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		entries, err := os.ReadDir(dirs[i])
+		if err != nil {
+			return removed, err
+		} else if len(entries) != 0 {
+			continue
+		}
+		err = os.Remove(dirs[i])
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, dirs[i])
+	}
+	return removed, nil
+}
+
+// Clean removes from dest the files and folders extracted from fsys.
+// Files not present in fsys are left untouched, so folders are only
+// removed if they are empty once the extracted files are gone.
+// Paths already missing from dest are ignored.
+func Clean(fsys fs.FS, dest string) error {
+	var dirs []string
+	err := Walk(fsys, ".", func(dirpath string, de fs.DirEntry) error {
+		fullpath, err := joinDest(dest, path.Join(dirpath, de.Name()))
+		if err != nil {
+			return &PathError{Path: path.Join(dirpath, de.Name()), Err: err}
+		}
+		if de.IsDir() {
+			dirs = append(dirs, fullpath)
+			return nil
+		}
+		err = os.Remove(fullpath)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	// Walk visits parents before children so remove in reverse.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		entries, err := os.ReadDir(dirs[i])
+		if os.IsNotExist(err) || len(entries) != 0 {
+			continue
+		} else if err != nil {
+			return err
+		}
+		err = os.Remove(dirs[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
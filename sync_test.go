@@ -0,0 +1,254 @@
+package rebed_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func TestSync(t *testing.T) {
+	dest := t.TempDir()
+	stray := []string{
+		filepath.Join(dest, "stray"),
+		filepath.Join(dest, "testFS", "strayfolder", "nested", "file"),
+		filepath.Join(dest, "testFS", "folder", "subfolder", "strayfile"),
+	}
+	for _, path := range stray {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte("stray"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := os.WriteFile(filepath.Join(dest, "testFS", "folder", "fileInfolder"), []byte("modified"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.Sync(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range append(stray, filepath.Join(dest, "testFS", "strayfolder")) {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %q to be removed, got %v", path, err)
+		}
+	}
+	assertMatchesFS(t, dest)
+}
+
+// assertMatchesFS checks every entry of testFS exists in dest
+// with the same contents.
+func assertMatchesFS(t *testing.T, dest string) {
+	t.Helper()
+	err := rebed.Walk(testFS, ".", func(path string, de fs.DirEntry) error {
+		embedPath := filepath.Join(path, de.Name())
+		info, err := os.Stat(filepath.Join(dest, embedPath))
+		if err != nil {
+			return err
+		}
+		if de.IsDir() != info.IsDir() {
+			t.Errorf("expected folder/file got file/folder %q", embedPath)
+		}
+		if de.IsDir() {
+			return nil
+		}
+		want, err := testFS.ReadFile(filepath.ToSlash(embedPath))
+		if err != nil {
+			return err
+		}
+		got, err := os.ReadFile(filepath.Join(dest, embedPath))
+		if err != nil {
+			return err
+		}
+		if string(got) != string(want) {
+			t.Errorf("%q: content mismatch, got %q, want %q", embedPath, got, want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	for _, pruneEmpty := range []bool{false, true} {
+		dest := t.TempDir()
+		err := rebed.CreateTo(testFS, dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// user removed an embedded file, leaving only a stray file in its folder.
+		subfolder := filepath.Join(dest, "testFS", "folder", "subfolder")
+		err = os.Remove(filepath.Join(subfolder, "fileinsubfolder"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stray := []string{
+			filepath.Join(subfolder, "strayfile"),
+			filepath.Join(dest, "testFS", "strayfile"),
+		}
+		for _, path := range stray {
+			err = os.WriteFile(path, []byte("stray"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		var opts []rebed.Option
+		if pruneEmpty {
+			opts = append(opts, rebed.WithPruneEmptyDirs())
+		}
+		removed, err := rebed.Prune(testFS, dest, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(removed)
+		want := []string{stray[1], stray[0]}
+		if pruneEmpty {
+			want = []string{stray[1], subfolder, stray[0]}
+		}
+		sort.Strings(want)
+		if strings.Join(removed, "\n") != strings.Join(want, "\n") {
+			t.Errorf("pruneEmpty=%v: got removed %q, want %q", pruneEmpty, removed, want)
+		}
+		_, err = os.Stat(subfolder)
+		if pruneEmpty != os.IsNotExist(err) {
+			t.Errorf("pruneEmpty=%v: unexpected subfolder stat result %v", pruneEmpty, err)
+		}
+		_, err = os.Stat(filepath.Join(dest, "testFS", "folder", "fileInfolder"))
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	dest := t.TempDir()
+	err := rebed.CreateTo(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unrelated := filepath.Join(dest, "testFS", "folder", "userfile")
+	err = os.WriteFile(unrelated, []byte("mine"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Remove(filepath.Join(dest, "testFS", "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.Clean(testFS, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("unrelated file removed: %v", err)
+	}
+	for _, removed := range []string{"testFS/file", "testFS/folder/fileInfolder", "testFS/folder/subfolder"} {
+		if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(removed))); !os.IsNotExist(err) {
+			t.Errorf("expected %q removed, got %v", removed, err)
+		}
+	}
+}
+
+func TestPruneEmptyDirs(t *testing.T) {
+	dest := t.TempDir()
+	for _, dir := range []string{"a/b/c", "a/d", "keep/e"} {
+		err := os.MkdirAll(filepath.Join(dest, filepath.FromSlash(dir)), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := os.WriteFile(filepath.Join(dest, "keep", "file"), []byte("x"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := rebed.PruneEmptyDirs(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, dir := range removed {
+		rel, err := filepath.Rel(dest, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.ToSlash(rel))
+	}
+	if want := []string{"keep/e", "a/d", "a/b/c", "a/b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got removed %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "keep", "file")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSyncGunzip(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt.gz": {Data: gzipData(t, "hello")},
+		"b.txt":    {Data: []byte("b")},
+	}
+	dest := t.TempDir()
+	err := os.WriteFile(filepath.Join(dest, "stray"), []byte("stray"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.Sync(fsys, dest, rebed.WithGunzip())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("got %q, want %q", got, "hello")
+	}
+	if _, err := os.Stat(filepath.Join(dest, "stray")); !os.IsNotExist(err) {
+		t.Errorf("expected stray file removed, got %v", err)
+	}
+}
+
+// gzipData returns s compressed with gzip.
+func gzipData(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(s))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSyncWriteFS(t *testing.T) {
+	dest := t.TempDir()
+	precious := filepath.Join(dest, "precious")
+	err := os.WriteFile(precious, []byte("precious"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rebed.Sync(testFS, dest, rebed.WithWriteFS(&rebed.MemFS{}))
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Sync: expected %v, got %v", errors.ErrUnsupported, err)
+	}
+	_, err = rebed.Prune(testFS, dest, rebed.WithWriteFS(&rebed.MemFS{}))
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Prune: expected %v, got %v", errors.ErrUnsupported, err)
+	}
+	if _, err := os.Stat(precious); err != nil {
+		t.Errorf("expected file on disk kept, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package rebed
+
+import (
+	"io"
+	"io/fs"
+	"text/template"
+)
+
+// CreateTemplate is like CreateTo but executes the embedded files as
+// text/template templates with data before writing them, i.e. to fill in
+// a {{.Version}} placeholder. Every file is a template unless
+// WithTemplateGlob restricts them.
+func CreateTemplate(fsys fs.FS, dest string, data interface{}, opts ...Option) error {
+	opts = append([]Option{func(c *config) {
+		if c.template == nil {
+			c.template = &templateConfig{}
+		}
+		c.template.data = data
+		c.template.enabled = true
+	}}, opts...)
+	return CreateTo(fsys, dest, opts...)
+}
+
+// WithTemplateGlob makes CreateTemplate only execute the files whose
+// embedded path matches pattern, see Globs for its syntax. Other files,
+// such as binary assets, are copied untouched. trimSuffix is removed from
+// the destination name of templates, i.e. WithTemplateGlob("**/*.tmpl", ".tmpl")
+// writes "config.yml.tmpl" to "config.yml". It has no effect on other functions.
+func WithTemplateGlob(pattern, trimSuffix string) Option {
+	return func(c *config) {
+		if c.template == nil {
+			c.template = &templateConfig{}
+		}
+		c.template.glob = pattern
+		c.template.trimSuffix = trimSuffix
+	}
+}
+
+type templateConfig struct {
+	enabled    bool // set by CreateTemplate.
+	data       interface{}
+	glob       string
+	trimSuffix string
+}
+
+// match reports whether the embedded file at path is a template.
+func (t *templateConfig) match(path string) (bool, error) {
+	if !t.enabled {
+		return false, nil
+	}
+	if t.glob == "" {
+		return true, nil
+	}
+	return matchGlob(t.glob, path)
+}
+
+// templated reports whether the embedded file at path is a template.
+func (c *config) templated(path string) (bool, error) {
+	if c.template == nil {
+		return false, nil
+	}
+	return c.template.match(path)
+}
+
+// executeTemplate parses the embedded file src at path as a template
+// and executes it with data into dst.
+func executeTemplate(dst io.Writer, src io.Reader, path string, data interface{}) error {
+	b, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	tmpl, err := template.New(path).Parse(string(b))
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(dst, data)
+}
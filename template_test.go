@@ -0,0 +1,69 @@
+package rebed_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func TestCreateTemplate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app/config.yml.tmpl": {Data: []byte("version: {{.Version}}\n")},
+		"app/logo.png":        {Data: []byte("\x89PNG{{.Version}}")},
+	}
+	data := struct{ Version string }{"1.2.3"}
+
+	dest := t.TempDir()
+	err := rebed.CreateTemplate(fsys, dest, data, rebed.WithTemplateGlob("**/*.tmpl", ".tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"app/config.yml": "version: 1.2.3\n",
+		"app/logo.png":   "\x89PNG{{.Version}}",
+	} {
+		got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%q: got %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dest, "app", "config.yml.tmpl")); !os.IsNotExist(err) {
+		t.Errorf("expected template suffix trimmed, got %v", err)
+	}
+
+	// without a glob every file is a template.
+	dest = t.TempDir()
+	err = rebed.CreateTemplate(fsys, dest, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "app", "logo.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "\x89PNG1.2.3" {
+		t.Errorf("expected all files executed, got %q", got)
+	}
+
+	// CreateTo ignores the template option.
+	dest = t.TempDir()
+	err = rebed.CreateTo(fsys, dest, rebed.WithTemplateGlob("**/*.tmpl", ".tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "app", "config.yml.tmpl")); err != nil {
+		t.Errorf("expected CreateTo to keep file names: %v", err)
+	}
+
+	fsys["app/bad.tmpl"] = &fstest.MapFile{Data: []byte("{{.Version")}
+	err = rebed.CreateTemplate(fsys, t.TempDir(), data)
+	if err == nil {
+		t.Error("expected error for malformed template")
+	}
+}
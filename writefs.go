@@ -0,0 +1,336 @@
+package rebed
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing/fstest"
+	"time"
+)
+
+// WriteFS is a writable filesystem extractions write through, see
+// WithWriteFS. Names are paths on disk as built by filepath.Join from
+// the destination folder. The OS filesystem is used by default.
+//
+// Some options need more than WriteFS provides and fail with
+// errors.ErrUnsupported unless it also has the matching method of the os
+// package: Remove and Rename for WithAtomicWrites and Backup decisions,
+// Chmod for WithFileModeFunc, Chtimes for WithModTime, Symlink for
+// WithSymlinks, Lstat for WithSymlinkGuard and Open, returning an fs.File,
+// to compare contents with CreateIfChanged and WithCheckpoint.
+type WriteFS interface {
+	// MkdirAll is like os.MkdirAll.
+	MkdirAll(name string, perm fs.FileMode) error
+	// OpenFile is like os.OpenFile for writing.
+	OpenFile(name string, flag int, perm fs.FileMode) (io.WriteCloser, error)
+	// Stat is like os.Stat.
+	Stat(name string) (fs.FileInfo, error)
+}
+
+// WithWriteFS makes extractions write through wfs instead of the OS
+// filesystem, i.e. to test them in memory with a MemFS. Sync and Prune,
+// which remove files, fail with errors.ErrUnsupported with it. Clean always
+// acts on the OS filesystem, as does WithCheckpoint's file.
+func WithWriteFS(wfs WriteFS) Option {
+	return func(c *config) { c.wfs = wfs }
+}
+
+// osFS is the WriteFS of the OS filesystem.
+type osFS struct{}
+
+func (osFS) MkdirAll(name string, perm fs.FileMode) error { return os.MkdirAll(name, perm) }
+func (osFS) Stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (osFS) Lstat(name string) (fs.FileInfo, error)       { return os.Lstat(name) }
+func (osFS) Open(name string) (fs.File, error)            { return os.Open(name) }
+func (osFS) Remove(name string) error                     { return os.Remove(name) }
+func (osFS) Rename(oldname, newname string) error         { return os.Rename(oldname, newname) }
+func (osFS) Chmod(name string, mode fs.FileMode) error    { return os.Chmod(name, mode) }
+func (osFS) Symlink(oldname, newname string) error        { return os.Symlink(oldname, newname) }
+
+func (osFS) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+func (osFS) OpenFile(name string, flag int, perm fs.FileMode) (io.WriteCloser, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err // not a nil *os.File.
+	}
+	return f, nil
+}
+
+func unsupported(op, name string) error {
+	return &fs.PathError{Op: op, Path: name, Err: errors.ErrUnsupported}
+}
+
+func removeFile(wfs WriteFS, name string) error {
+	if r, ok := wfs.(interface{ Remove(string) error }); ok {
+		return r.Remove(name)
+	}
+	return unsupported("remove", name)
+}
+
+func renameFile(wfs WriteFS, oldname, newname string) error {
+	if r, ok := wfs.(interface{ Rename(string, string) error }); ok {
+		return r.Rename(oldname, newname)
+	}
+	return unsupported("rename", oldname)
+}
+
+func chmodFile(wfs WriteFS, name string, mode fs.FileMode) error {
+	if c, ok := wfs.(interface {
+		Chmod(string, fs.FileMode) error
+	}); ok {
+		return c.Chmod(name, mode)
+	}
+	return unsupported("chmod", name)
+}
+
+func chtimesFile(wfs WriteFS, name string, atime, mtime time.Time) error {
+	if c, ok := wfs.(interface {
+		Chtimes(string, time.Time, time.Time) error
+	}); ok {
+		return c.Chtimes(name, atime, mtime)
+	}
+	return unsupported("chtimes", name)
+}
+
+func symlinkFile(wfs WriteFS, oldname, newname string) error {
+	if s, ok := wfs.(interface{ Symlink(string, string) error }); ok {
+		return s.Symlink(oldname, newname)
+	}
+	return unsupported("symlink", newname)
+}
+
+func openFile(wfs WriteFS, name string) (fs.File, error) {
+	if o, ok := wfs.(interface{ Open(string) (fs.File, error) }); ok {
+		return o.Open(name)
+	}
+	return nil, unsupported("open", name)
+}
+
+// MemFS is an in-memory WriteFS for testing extractions without touching
+// the disk. It implements every optional method of WriteFS but Symlink.
+// Names are cleaned and stored slash separated without a leading separator
+// or volume name, so "/tmp/out/a" and "tmp/out/a" are the same file; use a
+// relative destination folder such as "." to keep them short. The zero
+// value is an empty filesystem ready to use. It is safe for concurrent use.
+type MemFS struct {
+	mu    sync.Mutex
+	files fstest.MapFS
+}
+
+// key returns the name of a file in m.files.
+func (m *MemFS) key(name string) string {
+	name = filepath.Clean(name)
+	name = filepath.ToSlash(name[len(filepath.VolumeName(name)):])
+	name = strings.TrimLeft(name, "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// dir returns the folder k if it exists, failing if it is a file.
+func (m *MemFS) dir(op, name, k string) (exists bool, err error) {
+	if k == "." {
+		return true, nil
+	}
+	f, ok := m.files[k]
+	if ok && !f.Mode.IsDir() {
+		return false, &fs.PathError{Op: op, Path: name, Err: errNotDir}
+	}
+	return ok, nil
+}
+
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.files == nil {
+		m.files = make(fstest.MapFS)
+	}
+	k := m.key(name)
+	if k == "." {
+		return nil
+	}
+	elems := strings.Split(k, "/")
+	for i := range elems {
+		dir := strings.Join(elems[:i+1], "/")
+		exists, err := m.dir("mkdir", name, dir)
+		if err != nil {
+			return err
+		} else if !exists {
+			m.files[dir] = &fstest.MapFile{Mode: fs.ModeDir | perm.Perm(), ModTime: time.Now()}
+		}
+	}
+	return nil
+}
+
+func (m *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (io.WriteCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.files == nil {
+		m.files = make(fstest.MapFS)
+	}
+	k := m.key(name)
+	exists, err := m.dir("open", name, path.Dir(k))
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	f, ok := m.files[k]
+	switch {
+	case k == "." || ok && f.Mode.IsDir():
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errIsDir}
+	case ok && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		m.files[k] = &fstest.MapFile{Mode: perm.Perm(), ModTime: time.Now()}
+	case flag&os.O_TRUNC != 0:
+		f.Data = nil
+	}
+	w := &memWriter{m: m, name: k}
+	if flag&os.O_APPEND != 0 {
+		w.off = len(m.files[k].Data)
+	}
+	return w, nil
+}
+
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return fs.Stat(m.files, m.key(name))
+}
+
+// Open opens the file name for reading.
+func (m *MemFS) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.files.Open(m.key(name))
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := m.key(name)
+	if _, ok := m.files[k]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	for other := range m.files {
+		if strings.HasPrefix(other, k+"/") {
+			return &fs.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+		}
+	}
+	delete(m.files, k)
+	return nil
+}
+
+// Rename renames the file or folder oldname, replacing any file at newname.
+func (m *MemFS) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldk, newk := m.key(oldname), m.key(newname)
+	f, ok := m.files[oldk]
+	if !ok {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: fs.ErrNotExist}
+	}
+	if exists, err := m.dir("rename", newname, path.Dir(newk)); err != nil {
+		return err
+	} else if !exists {
+		return &fs.PathError{Op: "rename", Path: newname, Err: fs.ErrNotExist}
+	}
+	if g, ok := m.files[newk]; ok && g.Mode.IsDir() {
+		return &fs.PathError{Op: "rename", Path: newname, Err: errIsDir}
+	}
+	moved := map[string]*fstest.MapFile{newk: f}
+	delete(m.files, oldk)
+	for other, g := range m.files {
+		if strings.HasPrefix(other, oldk+"/") {
+			moved[newk+strings.TrimPrefix(other, oldk)] = g
+			delete(m.files, other)
+		}
+	}
+	for name, g := range moved {
+		m.files[name] = g
+	}
+	return nil
+}
+
+func (m *MemFS) Chmod(name string, mode fs.FileMode) error {
+	return m.update("chmod", name, func(f *fstest.MapFile) {
+		f.Mode = f.Mode&fs.ModeType | mode.Perm()
+	})
+}
+
+func (m *MemFS) Chtimes(name string, atime, mtime time.Time) error {
+	return m.update("chtimes", name, func(f *fstest.MapFile) { f.ModTime = mtime })
+}
+
+// update calls fn with the file or folder name under the lock.
+func (m *MemFS) update(op, name string, fn func(*fstest.MapFile)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[m.key(name)]
+	if !ok {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	fn(f)
+	return nil
+}
+
+// MapFS returns a copy of the contents of m, i.e.
+// to check them with fstest.TestFS.
+func (m *MemFS) MapFS() fstest.MapFS {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := make(fstest.MapFS, len(m.files))
+	for name, f := range m.files {
+		g := *f
+		g.Data = append([]byte(nil), f.Data...)
+		cp[name] = &g
+	}
+	return cp
+}
+
+// memWriter writes to a file of a MemFS.
+type memWriter struct {
+	m      *MemFS
+	name   string
+	off    int
+	closed bool
+}
+
+func (w *memWriter) Write(b []byte) (int, error) {
+	w.m.mu.Lock()
+	defer w.m.mu.Unlock()
+	f, ok := w.m.files[w.name]
+	if w.closed || !ok {
+		return 0, &fs.PathError{Op: "write", Path: w.name, Err: fs.ErrClosed}
+	}
+	end := w.off + len(b)
+	if end > len(f.Data) {
+		f.Data = append(f.Data, make([]byte, end-len(f.Data))...)
+	}
+	copy(f.Data[w.off:], b)
+	w.off = end
+	f.ModTime = time.Now()
+	return len(b), nil
+}
+
+func (w *memWriter) Close() error {
+	w.m.mu.Lock()
+	defer w.m.mu.Unlock()
+	if w.closed {
+		return &fs.PathError{Op: "close", Path: w.name, Err: fs.ErrClosed}
+	}
+	w.closed = true
+	return nil
+}
@@ -0,0 +1,129 @@
+package rebed_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soypat/rebed"
+)
+
+func TestWriteFS(t *testing.T) {
+	files := []string{"testFS/file", "testFS/folder/fileInfolder", "testFS/folder/subfolder/fileinsubfolder"}
+	for name, opts := range map[string][]rebed.Option{
+		"plain":  nil,
+		"atomic": {rebed.WithAtomicWrites()},
+		"modes":  {rebed.WithFileModeFunc(func(string) os.FileMode { return 0600 })},
+	} {
+		var mem rebed.MemFS
+		err := rebed.CreateTo(testFS, "out", append(opts, rebed.WithWriteFS(&mem))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub, err := fs.Sub(mem.MapFS(), "out")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = fstest.TestFS(sub, files...)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, file := range files {
+			got, err := fs.ReadFile(sub, file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := testFS.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("%s: %q: got %q, want %q", name, file, got, want)
+			}
+		}
+		written, err := rebed.CreateIfChanged(testFS, "out", rebed.WithWriteFS(&mem))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(written) != 0 {
+			t.Errorf("%s: expected no files rewritten, got %q", name, written)
+		}
+	}
+
+	var mem rebed.MemFS
+	created, err := rebed.TouchReport(testFS, "/abs", rebed.WithWriteFS(&mem))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 6 {
+		t.Errorf("expected 6 paths touched, got %q", created)
+	}
+	info, err := mem.Stat("/abs/testFS/folder/fileInfolder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("expected empty file, got size %d", info.Size())
+	}
+	err = rebed.CreateParallel(testFS, "/abs", 2, rebed.WithWriteFS(&mem))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mem.MapFS()["abs/testFS/file"]; got == nil || len(got.Data) != 5 {
+		t.Errorf("expected touched file overwritten, got %+v", got)
+	}
+
+	err = rebed.CreateTo(testFS, "out", rebed.WithWriteFS(&mem), rebed.WithSymlinks(func(string) (string, bool) {
+		return "target", true
+	}))
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("expected %v without Symlink method, got %v", errors.ErrUnsupported, err)
+	}
+}
+
+func TestMemFS(t *testing.T) {
+	var mem rebed.MemFS
+	err := mem.MkdirAll("a/b", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.OpenFile("missing/file", os.O_WRONLY|os.O_CREATE, 0644); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected missing parent to fail with fs.ErrNotExist, got %v", err)
+	}
+	w, err := mem.OpenFile("a/b/file", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("hello"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.OpenFile("a/b/file", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected O_EXCL to fail with fs.ErrExist, got %v", err)
+	}
+	if err := mem.MkdirAll("a/b/file/c", 0755); err == nil {
+		t.Error("expected MkdirAll through a file to fail")
+	}
+	if err := mem.Rename("a/b", "a/c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.Remove("a"); err == nil {
+		t.Error("expected removing non empty folder to fail")
+	}
+	var names []string
+	for name := range mem.MapFS() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if want := []string{"a", "a/c", "a/c/file"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %q, want %q", names, want)
+	}
+	b, err := fs.ReadFile(mem.MapFS(), "a/c/file")
+	if err != nil || string(b) != "hello" {
+		t.Errorf("got %q, %v", b, err)
+	}
+}